@@ -0,0 +1,127 @@
+// Package ai plays the game. It lists every placement the active piece can
+// reach, scores the board each one leaves with a weighted heuristic and turns
+// the best one into the frame by frame inputs the engine expects.
+package ai
+
+import (
+	"encoding/json"
+	"os"
+
+	"tetris/main/engine"
+)
+
+// Weights of each board feature in the heuristic score. Features that make
+// the board worse are expected to have negative weights.
+type Weights struct {
+	AggregateHeight float64 `json:"aggregateHeight"`
+	Holes           float64 `json:"holes"`
+	Bumpiness       float64 `json:"bumpiness"`
+	Wells           float64 `json:"wells"`
+	Lines           float64 `json:"lines"`
+}
+
+// DefaultWeights returns weights that play a steady game with the classic pieces
+func DefaultWeights() Weights {
+	return Weights{
+		AggregateHeight: -0.510066,
+		Holes:           -0.35663,
+		Bumpiness:       -0.184483,
+		Wells:           -0.1,
+		Lines:           0.760666,
+	}
+}
+
+// LoadWeights reads weights saved as JSON, features left out keeping their default weight
+func LoadWeights(path string) (Weights, error) {
+	weights := DefaultWeights()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return weights, err
+	}
+
+	err = json.Unmarshal(data, &weights)
+	return weights, err
+}
+
+// SaveWeights writes weights as JSON
+func SaveWeights(path string, weights Weights) error {
+	data, err := json.MarshalIndent(weights, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Vector returns the weights in a fixed order, for tuning
+func (w Weights) Vector() []float64 {
+	return []float64{w.AggregateHeight, w.Holes, w.Bumpiness, w.Wells, w.Lines}
+}
+
+// WeightsFromVector reads weights in the order Vector writes them
+func WeightsFromVector(v []float64) Weights {
+	return Weights{
+		AggregateHeight: v[0],
+		Holes:           v[1],
+		Bumpiness:       v[2],
+		Wells:           v[3],
+		Lines:           v[4],
+	}
+}
+
+// Features measured on a board after a piece has been locked
+type Features struct {
+	AggregateHeight int // Sum of the heights of every column
+	Holes           int // Empty squares with a filled square somewhere above
+	Bumpiness       int // Sum of the height differences between neighbour columns
+	Wells           int // Sum of the depths of columns lower than both neighbours
+	Lines           int // Lines cleared by the placement
+}
+
+// Score weighs the features of a board
+func (w Weights) Score(f Features) float64 {
+	return w.AggregateHeight*float64(f.AggregateHeight) +
+		w.Holes*float64(f.Holes) +
+		w.Bumpiness*float64(f.Bumpiness) +
+		w.Wells*float64(f.Wells) +
+		w.Lines*float64(f.Lines)
+}
+
+// Measure computes the features of a board, lines being the lines it took to get there
+func Measure(board *engine.Bitboard, lines int) Features {
+	f := Features{Lines: lines, Holes: board.Holes()}
+
+	heights := board.Heights()
+
+	for i := 1; i < engine.GridHorizontalSize-1; i++ {
+		f.AggregateHeight += heights[i]
+		if i > 1 {
+			f.Bumpiness += abs(heights[i] - heights[i-1])
+		}
+	}
+
+	for i := 1; i < engine.GridHorizontalSize-1; i++ {
+		// Walls count as infinitely high neighbours
+		left, right := engine.GridVerticalSize, engine.GridVerticalSize
+		if i > 1 {
+			left = heights[i-1]
+		}
+		if i < engine.GridHorizontalSize-2 {
+			right = heights[i+1]
+		}
+
+		if depth := min(left, right) - heights[i]; depth > 0 {
+			f.Wells += depth
+		}
+	}
+
+	return f
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
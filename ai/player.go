@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"math"
+
+	"tetris/main/engine"
+)
+
+// maxPlanFrames bounds a plan in case the piece never settles where expected
+const maxPlanFrames = 2000
+
+// Player picks a placement for every new piece and feeds the engine the
+// inputs that take the piece there, one frame at a time.
+type Player struct {
+	Weights   Weights
+	Lookahead bool // Also place the incoming piece before scoring a board
+
+	plan []engine.Input
+}
+
+// NewPlayer returns a player using the given weights, looking one piece ahead
+func NewPlayer(weights Weights) *Player {
+	return &Player{Weights: weights, Lookahead: true}
+}
+
+// Next returns the input for the coming frame of the game
+func (p *Player) Next(g *engine.Game) engine.Input {
+	if len(p.plan) == 0 {
+		if !g.PieceActive || g.GameOver {
+			return 0
+		}
+		p.plan = Plan(g, p.Best(g))
+	}
+
+	input := p.plan[0]
+	p.plan = p.plan[1:]
+
+	return input
+}
+
+// Reset drops the inputs left over from a previous game
+func (p *Player) Reset() {
+	p.plan = nil
+}
+
+// Best returns the best scored placement for the active piece, holding it
+// when the held (or incoming) piece does better.
+func (p *Player) Best(g *engine.Game) Placement {
+	board := g.Bits()
+	best := Placement{Type: g.PieceType, Rotation: g.PieceRotation, X: g.PiecePositionX, Y: g.PiecePositionY, Score: math.Inf(-1)}
+
+	next := g.IncomingType
+	for _, placement := range Placements(&board, g.Set, g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY) {
+		placement.Score = p.score(&board, g.Set, placement, next)
+		if placement.Score > best.Score {
+			best = placement
+		}
+	}
+
+	if !g.HoldUsed && (g.HoldType >= 0 || g.IncomingType >= 0) {
+		held := g.HoldType
+		if held < 0 {
+			// Holding for the first time takes the incoming piece, the one after it is unknown
+			held, next = g.IncomingType, -1
+		}
+
+		x := (engine.GridHorizontalSize - g.Set.Pieces[held].Size) / 2
+		for _, placement := range Placements(&board, g.Set, held, 0, x, 0) {
+			placement.Hold = true
+			placement.Score = p.score(&board, g.Set, placement, next)
+			if placement.Score > best.Score {
+				best = placement
+			}
+		}
+	}
+
+	return best
+}
+
+// score rates a placement, taking the best follow-up with the next piece into account when looking ahead
+func (p *Player) score(board *engine.Bitboard, set *engine.PieceSet, placement Placement, next int) float64 {
+	locked, lines := Lock(board, set, placement)
+	score := p.Weights.Score(Measure(&locked, lines))
+
+	if !p.Lookahead || next < 0 {
+		return score
+	}
+
+	x := (engine.GridHorizontalSize - set.Pieces[next].Size) / 2
+	best := math.Inf(-1)
+	for _, follow := range Placements(&locked, set, next, 0, x, 0) {
+		after, more := Lock(&locked, set, follow)
+		best = max(best, p.Weights.Score(Measure(&after, lines+more)))
+	}
+
+	// Nothing fits after this placement, keep the shallow score
+	if math.IsInf(best, -1) {
+		return score
+	}
+
+	return best
+}
+
+// Plan returns the inputs that take the active piece to a placement and
+// drop it there, ending on the frame the piece locks. It runs them on a
+// copy of the game so the sequence matches what the engine will do.
+func Plan(g *engine.Game, target Placement) []engine.Input {
+	var plan []engine.Input
+
+	sim := *g
+	last := engine.Input(0)
+
+	for sim.PieceActive && !sim.GameOver && len(plan) < maxPlanFrames {
+		var want engine.Input
+
+		switch {
+		case target.Hold && !sim.HoldUsed:
+			want = engine.InputHold
+		case sim.PieceRotation != target.Rotation:
+			want = engine.InputRotate
+		case sim.PiecePositionX < target.X:
+			want = engine.InputRight
+		case sim.PiecePositionX > target.X:
+			want = engine.InputLeft
+		default:
+			want = engine.InputDown
+		}
+
+		// Let go between presses, the engine only acts when a button goes down
+		if want != engine.InputDown && last&want != 0 {
+			want = 0
+		}
+
+		sim.Step(want)
+		plan = append(plan, want)
+		last = want
+	}
+
+	return plan
+}
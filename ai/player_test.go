@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"testing"
+
+	"tetris/main/engine"
+)
+
+// spawnedGame returns a new game stepped until its first piece is in play
+func spawnedGame(t *testing.T, seed int64) *engine.Game {
+	t.Helper()
+
+	g := engine.NewGame(engine.DefaultPieceSet(), seed)
+	for n := 0; !g.PieceActive; n++ {
+		if n > 100 {
+			t.Fatal("no piece spawned")
+		}
+		g.Step(0)
+	}
+
+	return g
+}
+
+// reachable lists the placements of the active piece and, held for the first time, of the incoming one
+func reachable(g *engine.Game) []Placement {
+	board := g.Bits()
+
+	placements := Placements(&board, g.Set, g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY)
+	if g.IncomingType >= 0 {
+		x := (engine.GridHorizontalSize - g.Set.Pieces[g.IncomingType].Size) / 2
+		for _, p := range Placements(&board, g.Set, g.IncomingType, 0, x, 0) {
+			p.Hold = true
+			placements = append(placements, p)
+		}
+	}
+
+	return placements
+}
+
+func TestPlanReachesPlacement(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		g := spawnedGame(t, seed)
+		board := g.Bits()
+
+		for _, target := range reachable(g) {
+			played := *g
+			for _, input := range Plan(g, target) {
+				played.Step(input)
+			}
+
+			want, _ := Lock(&board, g.Set, target)
+			if played.Bits() != want {
+				grid := played.Board()
+				t.Fatalf("seed %d: %s to %+v ended as\n%s", seed, g.Set.Pieces[target.Type].Name, target, engine.FormatBoard(&grid))
+			}
+		}
+	}
+}
+
+func TestBestIsReachable(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		g := spawnedGame(t, seed)
+		best := NewPlayer(DefaultWeights()).Best(g)
+		best.Score = 0
+
+		found := false
+		for _, p := range reachable(g) {
+			found = found || p == best
+		}
+		if !found {
+			t.Errorf("seed %d: best placement %+v is not one of the reachable ones", seed, best)
+		}
+	}
+}
+
+func TestPlayerClearsLines(t *testing.T) {
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	player := NewPlayer(DefaultWeights())
+
+	for frame := 0; frame < 20000 && g.Lines < 20; frame++ {
+		g.Step(player.Next(g))
+		if g.GameOver {
+			t.Fatalf("topped out after %d lines", g.Lines)
+		}
+	}
+
+	if g.Lines < 20 {
+		t.Errorf("cleared %d lines, want 20", g.Lines)
+	}
+}
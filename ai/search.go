@@ -0,0 +1,53 @@
+package ai
+
+import "tetris/main/engine"
+
+// Placement is where a piece ends up once dropped
+type Placement struct {
+	Hold     bool // Swap with the held piece before moving
+	Type     int
+	Rotation int
+	X        int
+	Y        int
+	Score    float64
+}
+
+// Placements lists every placement a piece can reach from the given position
+// the way the engine moves it: turning in place first, then sliding sideways
+// and finally falling straight down until it lands.
+func Placements(board *engine.Bitboard, set *engine.PieceSet, pieceType, rotation, x, y int) []Placement {
+	var placements []Placement
+
+	masks := set.Pieces[pieceType].Masks
+
+	for turns := 0; turns < len(masks); turns++ {
+		r := (rotation + turns) % len(masks)
+		mask := &masks[r]
+
+		// A blocked turn leaves the piece as it is, so later states are out of reach
+		if !board.Fits(mask, x, y) {
+			break
+		}
+
+		left := x
+		for board.Fits(mask, left-1, y) {
+			left--
+		}
+
+		for i := left; board.Fits(mask, i, y); i++ {
+			placements = append(placements, Placement{Type: pieceType, Rotation: r, X: i, Y: board.Drop(mask, i, y)})
+		}
+	}
+
+	return placements
+}
+
+// Lock returns the board with the placement locked in and complete lines removed, and the number of lines removed
+func Lock(board *engine.Bitboard, set *engine.PieceSet, p Placement) (engine.Bitboard, int) {
+	locked := *board
+	locked.Put(&set.Pieces[p.Type].Masks[p.Rotation], p.X, p.Y)
+
+	lines := locked.ClearLines()
+
+	return locked, lines
+}
@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+
+	"tetris/main/engine"
+)
+
+// The square by square search the bitboard replaced, kept as a reference for
+// the tests and the benchmarks
+
+func gridPlacements(grid *engine.Grid, set *engine.PieceSet, pieceType, rotation, x, y int) []Placement {
+	var placements []Placement
+
+	rotations := set.Pieces[pieceType].Rotations
+
+	for turns := 0; turns < len(rotations); turns++ {
+		r := (rotation + turns) % len(rotations)
+		shape := &rotations[r]
+
+		if !gridFits(grid, shape, x, y) {
+			break
+		}
+
+		left := x
+		for gridFits(grid, shape, left-1, y) {
+			left--
+		}
+
+		for i := left; gridFits(grid, shape, i, y); i++ {
+			j := y
+			for gridFits(grid, shape, i, j+1) {
+				j++
+			}
+
+			placements = append(placements, Placement{Type: pieceType, Rotation: r, X: i, Y: j})
+		}
+	}
+
+	return placements
+}
+
+func gridLock(grid *engine.Grid, set *engine.PieceSet, p Placement) (engine.Grid, int) {
+	board := *grid
+	shape := &set.Pieces[p.Type].Rotations[p.Rotation]
+
+	for i := 0; i < engine.MaxPieceSize; i++ {
+		for j := 0; j < engine.MaxPieceSize; j++ {
+			if shape[i][j] == engine.Moving {
+				board[p.X+i][p.Y+j] = engine.Full
+			}
+		}
+	}
+
+	lines := 0
+
+	to := engine.GridVerticalSize - 2
+	for from := engine.GridVerticalSize - 2; from >= 0; from-- {
+		complete := true
+		for i := 1; i < engine.GridHorizontalSize-1; i++ {
+			if board[i][from] == engine.Empty {
+				complete = false
+				break
+			}
+		}
+
+		if complete {
+			lines++
+			continue
+		}
+
+		if to != from {
+			for i := 1; i < engine.GridHorizontalSize-1; i++ {
+				board[i][to] = board[i][from]
+			}
+		}
+		to--
+	}
+
+	for ; to >= 0; to-- {
+		for i := 1; i < engine.GridHorizontalSize-1; i++ {
+			board[i][to] = engine.Empty
+		}
+	}
+
+	return board, lines
+}
+
+func gridMeasure(grid *engine.Grid, lines int) Features {
+	f := Features{Lines: lines}
+
+	var heights [engine.GridHorizontalSize]int
+
+	for i := 1; i < engine.GridHorizontalSize-1; i++ {
+		for j := 0; j < engine.GridVerticalSize-1; j++ {
+			if grid[i][j] != engine.Empty {
+				if heights[i] == 0 {
+					heights[i] = engine.GridVerticalSize - 1 - j
+				}
+			} else if heights[i] != 0 {
+				f.Holes++
+			}
+		}
+
+		f.AggregateHeight += heights[i]
+		if i > 1 {
+			f.Bumpiness += abs(heights[i] - heights[i-1])
+		}
+	}
+
+	for i := 1; i < engine.GridHorizontalSize-1; i++ {
+		left, right := engine.GridVerticalSize, engine.GridVerticalSize
+		if i > 1 {
+			left = heights[i-1]
+		}
+		if i < engine.GridHorizontalSize-2 {
+			right = heights[i+1]
+		}
+
+		if depth := min(left, right) - heights[i]; depth > 0 {
+			f.Wells += depth
+		}
+	}
+
+	return f
+}
+
+func gridFits(grid *engine.Grid, shape *engine.PieceShape, x, y int) bool {
+	for i := 0; i < engine.MaxPieceSize; i++ {
+		for j := 0; j < engine.MaxPieceSize; j++ {
+			if shape[i][j] != engine.Moving {
+				continue
+			}
+
+			gx, gy := x+i, y+j
+			if gx < 0 || gx >= engine.GridHorizontalSize || gy < 0 || gy >= engine.GridVerticalSize {
+				return false
+			}
+			if grid[gx][gy] != engine.Empty {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// testGrids returns grids stacked to every height, a few squares left empty in each row
+func testGrids() []engine.Grid {
+	random := engine.NewRandom(1)
+	var grids []engine.Grid
+
+	for height := 0; height < engine.GridVerticalSize-3; height++ {
+		grid := engine.NewGrid()
+		for j := engine.GridVerticalSize - 1 - height; j < engine.GridVerticalSize-1; j++ {
+			for i := 1; i < engine.GridHorizontalSize-1; i++ {
+				if random.Intn(4) != 0 {
+					grid[i][j] = engine.Full
+				}
+			}
+		}
+		grids = append(grids, grid)
+	}
+
+	return grids
+}
+
+func TestSearchMatchesGrid(t *testing.T) {
+	set := engine.DefaultPieceSet()
+
+	for _, grid := range testGrids() {
+		board := engine.NewBitboard(&grid)
+
+		for pieceType, piece := range set.Pieces {
+			x := (engine.GridHorizontalSize - piece.Size) / 2
+
+			placements := Placements(&board, set, pieceType, 0, x, 0)
+			if want := gridPlacements(&grid, set, pieceType, 0, x, 0); !slices.Equal(placements, want) {
+				t.Fatalf("%s: placements %v, want %v\n%s", piece.Name, placements, want, engine.FormatBoard(&grid))
+			}
+
+			for _, p := range placements {
+				locked, lines := Lock(&board, set, p)
+				lockedGrid, wantLines := gridLock(&grid, set, p)
+
+				if locked != engine.NewBitboard(&lockedGrid) || lines != wantLines {
+					t.Fatalf("%s at %d,%d: locked board differs\n%s", piece.Name, p.X, p.Y, engine.FormatBoard(&lockedGrid))
+				}
+				if got, want := Measure(&locked, lines), gridMeasure(&lockedGrid, wantLines); got != want {
+					t.Fatalf("%s at %d,%d: features %+v, want %+v", piece.Name, p.X, p.Y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkSearch scores every placement of every piece on boards of every height
+func BenchmarkSearch(b *testing.B) {
+	set := engine.DefaultPieceSet()
+	weights := DefaultWeights()
+	var boards []engine.Bitboard
+	for _, grid := range testGrids() {
+		boards = append(boards, engine.NewBitboard(&grid))
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := range boards {
+			for pieceType, piece := range set.Pieces {
+				for _, p := range Placements(&boards[i], set, pieceType, 0, (engine.GridHorizontalSize-piece.Size)/2, 0) {
+					locked, lines := Lock(&boards[i], set, p)
+					weights.Score(Measure(&locked, lines))
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkGridSearch is BenchmarkSearch with the square by square search
+func BenchmarkGridSearch(b *testing.B) {
+	set := engine.DefaultPieceSet()
+	weights := DefaultWeights()
+	grids := testGrids()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := range grids {
+			for pieceType, piece := range set.Pieces {
+				for _, p := range gridPlacements(&grids[i], set, pieceType, 0, (engine.GridHorizontalSize-piece.Size)/2, 0) {
+					locked, lines := gridLock(&grids[i], set, p)
+					weights.Score(gridMeasure(&locked, lines))
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkBest picks a move with one piece of lookahead, the way the AI player does every piece
+func BenchmarkBest(b *testing.B) {
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	for !g.PieceActive {
+		g.Step(0)
+	}
+	p := NewPlayer(DefaultWeights())
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		p.Best(g)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+	"tetris/main/synth"
+)
+
+// Audio settings, volumes from 0 to 1
+var (
+	soundVolume float32
+	musicVolume float32
+	muted       bool
+)
+
+// Audio tuning
+const (
+	VolumeStep    = 0.1
+	DangerHeight  = 0.6  // Share of the grid height the stack reaches before the music speeds up
+	MaxMusicSpeed = 1.25 // Speed of the music when the stack reaches the top
+)
+
+// MusicState is whether the music is stopped, paused or playing
+type MusicState int
+
+// Enumeration for MusicState
+const (
+	MusicStopped MusicState = iota
+	MusicPaused
+	MusicPlaying
+)
+
+// audio holds the sounds loaded into the audio device. Without a device it
+// stays empty and every function of this file does nothing.
+var audio struct {
+	ready  bool
+	sounds [synth.SoundCount]rl.Sound
+	music  rl.Sound
+	state  MusicState
+}
+
+// Sound watchers of the single player game and both versus players
+var (
+	gameSounds   SoundWatcher
+	versusSounds [2]SoundWatcher
+)
+
+// InitAudio opens the audio device and loads the sounds, leaving the game silent when there is no device
+func InitAudio() {
+	rl.InitAudioDevice()
+	if !rl.IsAudioDeviceReady() {
+		return
+	}
+
+	for sound := range audio.sounds {
+		audio.sounds[sound] = loadSound(synth.Samples(synth.Sound(sound)))
+	}
+	audio.music = loadSound(synth.Music())
+	audio.ready = true
+
+	ApplyVolumes()
+}
+
+// loadSound hands samples to the audio device, which keeps its own copy
+func loadSound(samples []int16) rl.Sound {
+	data := synth.WAV(samples)
+	wave := rl.LoadWaveFromMemory(".wav", data, int32(len(data)))
+	defer rl.UnloadWave(wave)
+
+	return rl.LoadSoundFromWave(wave)
+}
+
+// CloseAudio unloads the sounds and closes the audio device
+func CloseAudio() {
+	if !audio.ready {
+		return
+	}
+
+	for _, sound := range audio.sounds {
+		rl.UnloadSound(sound)
+	}
+	rl.UnloadSound(audio.music)
+	rl.CloseAudioDevice()
+	audio.ready = false
+}
+
+// ApplyVolumes sets the volume of every sound from the settings
+func ApplyVolumes() {
+	if !audio.ready {
+		return
+	}
+
+	master := float32(1)
+	if muted {
+		master = 0
+	}
+	rl.SetMasterVolume(master)
+
+	for _, sound := range audio.sounds {
+		rl.SetSoundVolume(sound, soundVolume)
+	}
+	rl.SetSoundVolume(audio.music, musicVolume)
+}
+
+// UpdateAudioKeys handles the mute key and the volume keys, on every screen
+func UpdateAudioKeys() {
+	changed := true
+
+	switch {
+	case rl.IsKeyPressed(rl.KeyM):
+		muted = !muted
+	case rl.IsKeyPressed(rl.KeyLeftBracket):
+		soundVolume = max(soundVolume-VolumeStep, 0)
+	case rl.IsKeyPressed(rl.KeyRightBracket):
+		soundVolume = min(soundVolume+VolumeStep, 1)
+	case rl.IsKeyPressed(rl.KeyMinus):
+		musicVolume = max(musicVolume-VolumeStep, 0)
+	case rl.IsKeyPressed(rl.KeyEqual):
+		musicVolume = min(musicVolume+VolumeStep, 1)
+	default:
+		changed = false
+	}
+
+	if changed {
+		ApplyVolumes()
+	}
+}
+
+// PlaySoundEffect plays a sound effect from its start
+func PlaySoundEffect(sound synth.Sound) {
+	if !audio.ready {
+		return
+	}
+
+	rl.PlaySound(audio.sounds[sound])
+}
+
+// UpdateMusic keeps the music looping while games are played, faster as the
+// highest stack nears the top, paused with the game and stopped off it
+func UpdateMusic() {
+	if !audio.ready {
+		return
+	}
+
+	var games []*engine.Game
+	over, paused := false, pause
+
+	switch screen {
+	case GameplayScreen, DemoScreen:
+		games = []*engine.Game{game}
+		over = game.GameOver || game.Finished
+	case VersusScreen, NetplayScreen:
+		games = match.Players[:]
+		over = match.Over() || networkError != nil
+	}
+
+	switch {
+	case games == nil || over:
+		if audio.state != MusicStopped {
+			rl.StopSound(audio.music)
+			audio.state = MusicStopped
+		}
+	case paused:
+		if audio.state == MusicPlaying {
+			rl.PauseSound(audio.music)
+			audio.state = MusicPaused
+		}
+	default:
+		if audio.state == MusicPaused {
+			rl.ResumeSound(audio.music)
+		} else if !rl.IsSoundPlaying(audio.music) {
+			// The music loops by starting over when it ends
+			rl.PlaySound(audio.music)
+		}
+		audio.state = MusicPlaying
+
+		danger := float32(0)
+		for _, g := range games {
+			danger = max(danger, Danger(g))
+		}
+		rl.SetSoundPitch(audio.music, 1+(MaxMusicSpeed-1)*danger)
+	}
+}
+
+// Danger returns how close the stack of a game is to the top, from 0 below
+// DangerHeight to 1 at the top
+func Danger(g *engine.Game) float32 {
+	bits := g.Bits()
+	height := 0
+	for _, h := range bits.Heights() {
+		height = max(height, h)
+	}
+
+	share := float32(height) / (engine.GridVerticalSize - 1)
+	return min(max((share-DangerHeight)/(1-DangerHeight), 0), 1)
+}
+
+// SoundWatcher plays the sounds of whatever happened in the last step of a game
+type SoundWatcher struct {
+	// State of the game when last seen
+	seen      bool
+	frame     int
+	pieces    int
+	pieceType int
+	x         int
+	rotation  int
+	active    bool
+	holdUsed  bool
+	deleting  bool
+	level     int
+	over      bool
+}
+
+// Observe compares a game with the last time it was seen and plays the sound of the change
+func (w *SoundWatcher) Observe(g *engine.Game) {
+	last := *w
+	*w = SoundWatcher{
+		seen:      true,
+		frame:     g.Frame,
+		pieces:    g.Pieces,
+		pieceType: g.PieceType,
+		x:         g.PiecePositionX,
+		rotation:  g.PieceRotation,
+		active:    g.PieceActive,
+		holdUsed:  g.HoldUsed,
+		deleting:  g.LineToDelete,
+		level:     g.Level,
+		over:      g.GameOver,
+	}
+
+	// A new game starts quietly
+	if !last.seen || g.Frame < last.frame {
+		return
+	}
+
+	switch {
+	case g.GameOver && !last.over:
+		PlaySoundEffect(synth.GameOver)
+	case g.LineToDelete && !last.deleting:
+		lines := 0
+		for j := 0; j < engine.GridVerticalSize-1; j++ {
+			if g.Grid[1][j] == engine.Fading {
+				lines++
+			}
+		}
+		PlaySoundEffect(synth.Clear(lines))
+	case g.Level > last.level:
+		PlaySoundEffect(synth.LevelUp)
+	case g.Pieces > last.pieces:
+		PlaySoundEffect(synth.Lock)
+	case !g.PieceActive || !last.active || g.PieceType != last.pieceType || g.HoldUsed != last.holdUsed:
+		// A new or held piece makes no sound of its own
+	case g.PieceRotation != last.rotation:
+		PlaySoundEffect(synth.Rotate)
+	case g.PiecePositionX != last.x:
+		PlaySoundEffect(synth.Move)
+	}
+}
@@ -0,0 +1,193 @@
+package botapi
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+// dropBot lets every piece fall where it spawns, using raw inputs
+type dropBot struct{}
+
+func (dropBot) Decide(hello *Hello, state *State) Command {
+	return Command{Inputs: []string{"down"}}
+}
+
+func TestMatch(t *testing.T) {
+	config := Config{Set: engine.DefaultPieceSet(), Seed: 7, MaxPieces: 60}
+
+	results, err := Match(config, NewHeuristicBot(ai.DefaultWeights()), dropBot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heuristic, drop := results[0], results[1]
+
+	if heuristic.Reason != "limit" || heuristic.Pieces != 60 {
+		t.Errorf("heuristic bot ended with %+v, want to reach the piece limit", heuristic)
+	}
+	if heuristic.Lines == 0 {
+		t.Errorf("heuristic bot cleared no lines")
+	}
+	if drop.Reason != "gameover" || drop.Lines != 0 {
+		t.Errorf("drop bot ended with %+v, want a game over without lines", drop)
+	}
+}
+
+// rejectedOnceBot answers the first state with a placement out of reach, then drops every piece
+type rejectedOnceBot struct {
+	frames []int
+}
+
+func (b *rejectedOnceBot) Decide(hello *Hello, state *State) Command {
+	b.frames = append(b.frames, state.Frame)
+	if len(b.frames) == 1 {
+		return Command{Place: &Placement{Rotation: 9}}
+	}
+	return Command{Inputs: []string{"down"}}
+}
+
+func TestRejectedCommandGetsTheStateAgain(t *testing.T) {
+	bot := &rejectedOnceBot{}
+	results, err := Match(Config{Set: engine.DefaultPieceSet(), Seed: 1, MaxPieces: 5}, bot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].Reason != "limit" || results[0].Pieces != 5 {
+		t.Errorf("game ended with %+v, want to reach the piece limit", results[0])
+	}
+	if len(bot.frames) < 2 || bot.frames[1] != bot.frames[0] {
+		t.Errorf("states on frames %v, want the rejected one sent again", bot.frames)
+	}
+}
+
+func TestServeSockets(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:0", "unix:" + filepath.Join(t.TempDir(), "tetris.sock")} {
+		l, err := Listen(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go Serve(l, Config{Set: engine.DefaultPieceSet(), Seed: 1, MaxPieces: 10, Timeout: 5 * time.Second})
+
+		// The listener knows the port picked for TCP
+		dial := l.Addr().String()
+		if network, _ := splitAddress(address); network == "unix" {
+			dial = address
+		}
+
+		c, err := Dial(dial)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		end, err := c.Play(NewHeuristicBot(ai.DefaultWeights()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if end.Pieces != 10 {
+			t.Errorf("%s: game ended after %d pieces, want 10", address, end.Pieces)
+		}
+
+		c.Close()
+		l.Close()
+	}
+}
+
+func TestRejectedCommand(t *testing.T) {
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	for !g.PieceActive {
+		g.Step(0)
+	}
+
+	for _, line := range []string{
+		`{"inputs": ["jump"]}`,
+		`{"place": {"rotation": 9, "x": 3}}`,
+		`{"place": {"rotation": 0, "x": 11}}`,
+		`{"place": {"rotation": 0, "x": -3}}`,
+		`not json`,
+	} {
+		if _, err := commandInputs([]byte(line), g); err == nil {
+			t.Errorf("command %s accepted", line)
+		}
+	}
+
+	inputs, err := commandInputs([]byte(`{"inputs": ["left+down", ""]}`), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 || inputs[0] != engine.InputLeft|engine.InputDown || inputs[1] != 0 {
+		t.Errorf("got inputs %v", inputs)
+	}
+}
+
+func TestPlaceCommand(t *testing.T) {
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	for !g.PieceActive {
+		g.Step(0)
+	}
+
+	active := g.PieceType
+	inputs, err := commandInputs([]byte(`{"place": {"rotation": 1, "x": 1, "hold": true}}`), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		g.Step(input)
+	}
+
+	if g.HoldType != active || g.Pieces != 1 {
+		t.Errorf("placing the incoming piece left %d held after %d pieces, want %d after 1", g.HoldType, g.Pieces, active)
+	}
+}
+
+// A puzzle whose queue has run out has nothing to hold
+func TestHoldWithEmptyQueue(t *testing.T) {
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	g.QueueOnly = true
+	for !g.PieceActive {
+		g.Step(0)
+	}
+	g.IncomingType = -1
+
+	if _, err := commandInputs([]byte(`{"place": {"rotation": 0, "x": 3, "hold": true}}`), g); err == nil {
+		t.Error("holding with nothing to hold accepted")
+	}
+}
+
+func TestHelloPieceSet(t *testing.T) {
+	set, err := engine.LoadPieceSet("../piecesets/bigblock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(Hello{Type: TypeHello, Version: Version, Set: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hello Hello
+	if err := json.Unmarshal(data, &hello); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hello.Set.Pieces) != len(set.Pieces) {
+		t.Fatalf("got %d pieces, want %d", len(hello.Set.Pieces), len(set.Pieces))
+	}
+	for i, p := range set.Pieces {
+		q := hello.Set.Pieces[i]
+		if p.Name != q.Name || p.Weight != q.Weight || p.Size != q.Size || len(p.Rotations) != len(q.Rotations) {
+			t.Fatalf("piece %d changed from %+v to %+v", i, p, q)
+		}
+		for r := range p.Rotations {
+			if p.Rotations[r] != q.Rotations[r] {
+				t.Errorf("piece %s rotation %d changed", p.Name, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package botapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+// Bot decides what to do with each piece
+type Bot interface {
+	Decide(hello *Hello, state *State) Command
+}
+
+// Client is the bot end of a connection to the server
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	encoder *json.Encoder
+	Hello   Hello
+}
+
+// Dial connects to a server and reads its hello message
+func Dial(address string) (*Client, error) {
+	conn, err := net.Dial(splitAddress(address))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient starts the bot end of an open connection, reading the hello message
+func NewClient(conn net.Conn) (*Client, error) {
+	c := &Client{
+		conn:    conn,
+		scanner: bufio.NewScanner(conn),
+		encoder: json.NewEncoder(conn),
+	}
+	c.scanner.Buffer(nil, maxLine)
+
+	kind, line, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if kind != TypeHello {
+		return nil, fmt.Errorf("expected hello, got %q", kind)
+	}
+	if err := json.Unmarshal(line, &c.Hello); err != nil {
+		return nil, err
+	}
+	if c.Hello.Version != Version {
+		return nil, fmt.Errorf("unsupported protocol version %d", c.Hello.Version)
+	}
+
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Play answers every state with the bot's command until the game ends,
+// logging the commands the server rejects
+func (c *Client) Play(bot Bot) (End, error) {
+	for {
+		kind, line, err := c.read()
+		if err != nil {
+			return End{}, err
+		}
+
+		switch kind {
+		case TypeState:
+			var state State
+			if err := json.Unmarshal(line, &state); err != nil {
+				return End{}, err
+			}
+			if err := c.encoder.Encode(bot.Decide(&c.Hello, &state)); err != nil {
+				return End{}, err
+			}
+		case TypeError:
+			// The state comes again for a new answer
+			var e Error
+			json.Unmarshal(line, &e)
+			log.Printf("server rejected command: %s", e.Error)
+		case TypeEnd:
+			var end End
+			err := json.Unmarshal(line, &end)
+			return end, err
+		default:
+			return End{}, fmt.Errorf("unexpected message %q", kind)
+		}
+	}
+}
+
+// read returns the next message and its type
+func (c *Client) read() (string, []byte, error) {
+	if !c.scanner.Scan() {
+		if c.scanner.Err() != nil {
+			return "", nil, c.scanner.Err()
+		}
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(c.scanner.Bytes(), &header); err != nil {
+		return "", nil, err
+	}
+
+	return header.Type, c.scanner.Bytes(), nil
+}
+
+// HeuristicBot is the reference bot, choosing placements with the ai package
+type HeuristicBot struct {
+	Player *ai.Player
+}
+
+// NewHeuristicBot returns a reference bot scoring boards with the given weights
+func NewHeuristicBot(weights ai.Weights) *HeuristicBot {
+	return &HeuristicBot{Player: ai.NewPlayer(weights)}
+}
+
+// Decide places the piece where the heuristic scores best
+func (b *HeuristicBot) Decide(hello *Hello, state *State) Command {
+	g := engine.Game{
+		Set:            hello.Set,
+		PieceType:      state.Piece.Type,
+		PieceRotation:  state.Piece.Rotation,
+		PiecePositionX: state.Piece.X,
+		PiecePositionY: state.Piece.Y,
+		PieceActive:    true,
+		IncomingType:   -1,
+		HoldType:       state.Hold,
+		HoldUsed:       !state.CanHold,
+	}
+	g.SetGrid(state.Grid())
+	if len(state.Queue) > 0 {
+		g.IncomingType = state.Queue[0]
+	}
+
+	best := b.Player.Best(&g)
+
+	return Command{Place: &Placement{Hold: best.Hold, Rotation: best.Rotation, X: best.X}}
+}
+
+// Match plays every bot against the same game over in-memory connections
+// and returns their results in order
+func Match(config Config, bots ...Bot) ([]End, error) {
+	results := make([]End, len(bots))
+	errs := make(chan error, len(bots))
+
+	for n, bot := range bots {
+		server, client := net.Pipe()
+
+		go func() {
+			defer server.Close()
+			Play(server, config)
+		}()
+
+		go func() {
+			defer client.Close()
+
+			c, err := NewClient(client)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results[n], err = c.Play(bot)
+			errs <- err
+		}()
+	}
+
+	var first error
+	for range bots {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return results, first
+}
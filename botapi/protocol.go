@@ -0,0 +1,178 @@
+// Package botapi exposes the engine to bots written in any language over a
+// local socket, speaking one JSON object per line.
+//
+// On connection the server sends a hello message with the piece set. Every
+// time a new piece waits for a decision it sends a state message, and the
+// bot answers with a command: either a placement, which the server plays
+// with the same moves as the built-in AI, or raw per-frame inputs. After raw
+// inputs the server sends the state again if the piece is still falling.
+// A command the server cannot play gets an error message, followed by the
+// state again for a new answer.
+// An end message closes the game.
+//
+// Board rows cover the playfield inside the walls, top row first, with '.'
+// for empty and '#' for full squares: character i of row j is grid square
+// (i+1, j). Piece positions are the grid position of the top left corner of
+// the piece box.
+package botapi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"tetris/main/engine"
+)
+
+// Version of the protocol, sent in the hello message
+const Version = 1
+
+// Message types
+const (
+	TypeHello = "hello"
+	TypeState = "state"
+	TypeError = "error"
+	TypeEnd   = "end"
+)
+
+// Hello is the first message a bot receives
+type Hello struct {
+	Type    string           `json:"type"`
+	Version int              `json:"version"`
+	Width   int              `json:"width"`
+	Height  int              `json:"height"`
+	Set     *engine.PieceSet `json:"set"`
+}
+
+// State is sent every time the game waits for a decision
+type State struct {
+	Type    string   `json:"type"`
+	Frame   int      `json:"frame"`
+	Lines   int      `json:"lines"`
+	Pieces  int      `json:"pieces"`
+	Board   []string `json:"board"`
+	Piece   Piece    `json:"piece"`
+	Queue   []int    `json:"queue"`
+	Hold    int      `json:"hold"` // -1 while nothing is held
+	CanHold bool     `json:"canHold"`
+}
+
+// Piece is the active piece, its type indexing the pieces of the set
+type Piece struct {
+	Type     int `json:"type"`
+	Rotation int `json:"rotation"`
+	X        int `json:"x"`
+	Y        int `json:"y"`
+}
+
+// Command is a bot answer, either a placement or raw inputs
+type Command struct {
+	Place  *Placement `json:"place,omitempty"`
+	Inputs []string   `json:"inputs,omitempty"` // One entry per frame, buttons joined by '+'
+}
+
+// Placement asks the server to move the piece to a rotation and column and drop it
+type Placement struct {
+	Hold     bool `json:"hold"`
+	Rotation int  `json:"rotation"`
+	X        int  `json:"x"`
+}
+
+// Error reports a command the server could not play; the state follows for the bot to answer again
+type Error struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// End closes a game
+type End struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"` // "gameover" or "limit"
+	Frame  int    `json:"frame"`
+	Lines  int    `json:"lines"`
+	Pieces int    `json:"pieces"`
+}
+
+// buttons names each engine button in raw inputs
+var buttons = []struct {
+	name  string
+	input engine.Input
+}{
+	{"left", engine.InputLeft},
+	{"right", engine.InputRight},
+	{"rotate", engine.InputRotate},
+	{"down", engine.InputDown},
+	{"hold", engine.InputHold},
+}
+
+// ParseInput reads the buttons of one frame, such as "left+down", "" being no button
+func ParseInput(s string) (engine.Input, error) {
+	var input engine.Input
+
+	if s == "" {
+		return input, nil
+	}
+
+	for _, name := range strings.Split(s, "+") {
+		found := false
+		for _, b := range buttons {
+			if b.name == name {
+				input |= b.input
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+	}
+
+	return input, nil
+}
+
+// FormatInput writes the buttons of one frame the way ParseInput reads them
+func FormatInput(input engine.Input) string {
+	var names []string
+
+	for _, b := range buttons {
+		if input&b.input != 0 {
+			names = append(names, b.name)
+		}
+	}
+
+	return strings.Join(names, "+")
+}
+
+// Grid rebuilds the engine grid of a state, without the active piece. The
+// rows come from the server so they are always well formed.
+func (s *State) Grid() engine.Grid {
+	grid, _ := engine.ParseRows(s.Board)
+	return grid
+}
+
+// boardRows writes the playfield of a grid in board notation, lines still fading shown full
+func boardRows(grid *engine.Grid) []string {
+	settled := *grid
+
+	for i := range settled {
+		for j := range settled[i] {
+			if settled[i][j] == engine.Fading {
+				settled[i][j] = engine.Full
+			}
+		}
+	}
+
+	return engine.FormatRows(&settled)
+}
+
+// Listen opens a local socket, "unix:" prefixing the path of a Unix socket
+func Listen(address string) (net.Listener, error) {
+	return net.Listen(splitAddress(address))
+}
+
+// splitAddress returns the network and address of a socket address
+func splitAddress(address string) (string, string) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", address
+}
@@ -0,0 +1,199 @@
+package botapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+// maxLine bounds the size of a command line
+const maxLine = 1 << 20
+
+// Config of the games played by the server
+type Config struct {
+	Set       *engine.PieceSet
+	Seed      int64
+	MaxPieces int           // End the game after this many locked pieces, 0 for no limit
+	Timeout   time.Duration // Time a bot has to answer, 0 to wait forever
+}
+
+// Serve accepts bots on the listener and plays one game with each, until the listener is closed
+func Serve(l net.Listener, config Config) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			Play(conn, config)
+		}()
+	}
+}
+
+// Play runs one game for the bot on the other end of the connection
+func Play(conn net.Conn, config Config) (End, error) {
+	g := engine.NewGame(config.Set, config.Seed)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxLine)
+	encoder := json.NewEncoder(conn)
+
+	err := encoder.Encode(Hello{
+		Type:    TypeHello,
+		Version: Version,
+		Width:   engine.GridHorizontalSize - 2,
+		Height:  engine.GridVerticalSize - 1,
+		Set:     config.Set,
+	})
+	if err != nil {
+		return End{}, err
+	}
+
+	end := End{Type: TypeEnd, Reason: "gameover"}
+
+	for {
+		// Run the engine until a piece waits for a decision
+		for !g.PieceActive && !g.GameOver {
+			g.Step(0)
+		}
+		if g.GameOver {
+			break
+		}
+		if config.MaxPieces > 0 && g.Pieces >= config.MaxPieces {
+			end.Reason = "limit"
+			break
+		}
+
+		if err := encoder.Encode(gameState(g)); err != nil {
+			return End{}, err
+		}
+
+		inputs, err := readCommand(conn, scanner, encoder, g, config.Timeout)
+		if err != nil {
+			return End{}, err
+		}
+
+		for _, input := range inputs {
+			g.Step(input)
+		}
+	}
+
+	end.Frame = g.Frame
+	end.Lines = g.Lines
+	end.Pieces = g.Pieces
+
+	return end, encoder.Encode(end)
+}
+
+// gameState describes the game waiting for a decision
+func gameState(g *engine.Game) State {
+	return State{
+		Type:    TypeState,
+		Frame:   g.Frame,
+		Lines:   g.Lines,
+		Pieces:  g.Pieces,
+		Board:   boardRows(&g.Grid),
+		Piece:   Piece{Type: g.PieceType, Rotation: g.PieceRotation, X: g.PiecePositionX, Y: g.PiecePositionY},
+		Queue:   []int{g.IncomingType},
+		Hold:    g.HoldType,
+		CanHold: !g.HoldUsed,
+	}
+}
+
+// readCommand waits for a command the game can play and turns it into inputs,
+// telling the bot about every command that is rejected and sending the state
+// again for a new answer
+func readCommand(conn net.Conn, scanner *bufio.Scanner, encoder *json.Encoder, g *engine.Game, timeout time.Duration) ([]engine.Input, error) {
+	for {
+		if timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		if !scanner.Scan() {
+			if scanner.Err() != nil {
+				return nil, scanner.Err()
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		inputs, err := commandInputs(scanner.Bytes(), g)
+		if err == nil {
+			return inputs, nil
+		}
+
+		if err := encoder.Encode(Error{Type: TypeError, Error: err.Error()}); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(gameState(g)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// commandInputs decodes a command into the inputs to play this frame onwards
+func commandInputs(line []byte, g *engine.Game) ([]engine.Input, error) {
+	var command Command
+	if err := json.Unmarshal(line, &command); err != nil {
+		return nil, err
+	}
+
+	if place := command.Place; place != nil {
+		if !g.PieceActive {
+			return nil, fmt.Errorf("no piece to place")
+		}
+
+		// The piece moves from where it is, or from where a held piece spawns
+		pieceType, rotation, x, y := g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY
+		if place.Hold {
+			if g.HoldUsed {
+				return nil, fmt.Errorf("hold already used for this piece")
+			}
+			pieceType = g.HoldType
+			if pieceType < 0 {
+				pieceType = g.IncomingType
+			}
+			if pieceType < 0 {
+				return nil, fmt.Errorf("no piece to hold")
+			}
+			rotation, x, y = 0, (engine.GridHorizontalSize-g.Set.Pieces[pieceType].Size)/2, 0
+		}
+
+		if place.Rotation < 0 || place.Rotation >= len(g.Set.Pieces[pieceType].Rotations) {
+			return nil, fmt.Errorf("rotation %d out of range", place.Rotation)
+		}
+
+		board := g.Bits()
+		for _, placement := range ai.Placements(&board, g.Set, pieceType, rotation, x, y) {
+			if placement.Rotation == place.Rotation && placement.X == place.X {
+				placement.Hold = place.Hold
+				return ai.Plan(g, placement), nil
+			}
+		}
+
+		return nil, fmt.Errorf("rotation %d at column %d cannot be reached", place.Rotation, place.X)
+	}
+
+	// Play at least one frame so the game always moves forward
+	inputs := []engine.Input{0}
+	if len(command.Inputs) > 0 {
+		inputs = inputs[:0]
+	}
+
+	for _, s := range command.Inputs {
+		input, err := ParseInput(s)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"tetris/main/ai"
+	"tetris/main/botapi"
+	"tetris/main/engine"
+	"tetris/main/tune"
+)
+
+// RunCommand runs a headless subcommand and returns the exit code
+func RunCommand(name string, args []string) int {
+	var err error
+
+	switch name {
+	case "serve":
+		err = ServeCommand(args)
+	case "bot":
+		err = BotCommand(args)
+	case "tune":
+		err = TuneCommand(args)
+	default:
+		err = fmt.Errorf("unknown command %q", name)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// ServeCommand exposes the engine to external bots over a local socket
+func ServeCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	address := flags.String("listen", "127.0.0.1:7777", "address to listen on, unix:PATH for a Unix socket")
+	piecesPath := flags.String("pieces", "", "load the pieces from a piece set definition file")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the games played")
+	maxPieces := flags.Int("max-pieces", 0, "end games after this many pieces, 0 for no limit")
+	timeout := flags.Duration("timeout", 0, "time a bot has to answer, 0 to wait forever")
+	flags.Parse(args)
+
+	set, err := LoadPieceSetFlag(*piecesPath)
+	if err != nil {
+		return err
+	}
+
+	l, err := botapi.Listen(*address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	fmt.Fprintf(os.Stderr, "waiting for bots on %s\n", l.Addr())
+
+	return botapi.Serve(l, botapi.Config{Set: set, Seed: *seed, MaxPieces: *maxPieces, Timeout: *timeout})
+}
+
+// BotCommand plays a game on a bot server with the reference heuristic bot
+func BotCommand(args []string) error {
+	flags := flag.NewFlagSet("bot", flag.ExitOnError)
+	address := flags.String("connect", "127.0.0.1:7777", "address of the server, unix:PATH for a Unix socket")
+	weightsPath := flags.String("weights", "", "load the AI weights from a file written by tune")
+	flags.Parse(args)
+
+	weights, err := LoadWeightsFlag(*weightsPath)
+	if err != nil {
+		return err
+	}
+
+	c, err := botapi.Dial(*address)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	end, err := c.Play(botapi.NewHeuristicBot(weights))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s after %d pieces: %d lines in %d frames\n", end.Reason, end.Pieces, end.Lines, end.Frame)
+	return nil
+}
+
+// TuneCommand evolves the AI weights over many headless games
+func TuneCommand(args []string) error {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	piecesPath := flags.String("pieces", "", "load the pieces from a piece set definition file")
+	startPath := flags.String("weights", "", "start from the AI weights in this file")
+	output := flags.String("out", "weights.json", "file the best weights are written to")
+	reportPath := flags.String("report", "tune.csv", "file the per-generation CSV report is written to")
+	config := tune.Config{}
+	flags.IntVar(&config.Generations, "generations", 20, "number of generations")
+	flags.IntVar(&config.Population, "population", 40, "weight vectors tried every generation")
+	flags.IntVar(&config.Elite, "elite", 8, "best vectors the next generation is drawn around")
+	flags.IntVar(&config.Games, "games", 4, "games played by every vector")
+	flags.IntVar(&config.MaxPieces, "max-pieces", 300, "pieces after which a game stops, 0 for no limit")
+	flags.IntVar(&config.Workers, "workers", runtime.NumCPU(), "games played at the same time")
+	flags.BoolVar(&config.Lookahead, "lookahead", false, "let the players look at the incoming piece")
+	flags.Float64Var(&config.Noise, "noise", 0.2, "extra spread added to the first generations")
+	flags.Int64Var(&config.Seed, "seed", time.Now().UnixNano(), "seed of the run")
+	flags.Parse(args)
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	if config.Set, err = LoadPieceSetFlag(*piecesPath); err != nil {
+		return err
+	}
+	if config.Start, err = LoadWeightsFlag(*startPath); err != nil {
+		return err
+	}
+
+	file, err := os.Create(*reportPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report := csv.NewWriter(file)
+	report.Write([]string{
+		"generation", "best", "mean", "worst",
+		"best_height", "best_holes", "best_bumpiness", "best_wells", "best_lines",
+		"mean_height", "mean_holes", "mean_bumpiness", "mean_wells", "mean_lines",
+	})
+
+	best := tune.Run(config, func(g tune.Generation) {
+		row := []string{strconv.Itoa(g.Number), formatFloat(g.Best.Fitness), formatFloat(g.Mean), formatFloat(g.Worst)}
+		for _, w := range g.Best.Weights.Vector() {
+			row = append(row, formatFloat(w))
+		}
+		for _, w := range g.Center.Vector() {
+			row = append(row, formatFloat(w))
+		}
+		report.Write(row)
+		report.Flush()
+
+		fmt.Fprintf(os.Stderr, "generation %d: best %.1f lines, mean %.1f\n", g.Number, g.Best.Fitness, g.Mean)
+	})
+
+	if err := report.Error(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "best %.1f lines, weights written to %s\n", best.Fitness, *output)
+	return ai.SaveWeights(*output, best.Weights)
+}
+
+// LoadWeightsFlag loads the AI weights named by a -weights flag, the default ones when empty
+func LoadWeightsFlag(path string) (ai.Weights, error) {
+	if path == "" {
+		return ai.DefaultWeights(), nil
+	}
+	return ai.LoadWeights(path)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
+
+// LoadPieceSetFlag loads the piece set named by a -pieces flag, the classic one when empty
+func LoadPieceSetFlag(path string) (*engine.PieceSet, error) {
+	if path == "" {
+		return engine.DefaultPieceSet(), nil
+	}
+	return engine.LoadPieceSet(path)
+}
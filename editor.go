@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+)
+
+var (
+	editorPuzzle  engine.Puzzle  // Puzzle being edited, its board aside
+	editorGrid    engine.Grid    // Board being edited
+	editorPlaying *engine.Puzzle // Board played from the editor
+	editorPath    string         // File the editor saves to and loads from
+	editorMessage string         // Outcome of the last save or load
+)
+
+// EditorOffset returns the position of the edited grid on screen, the panel of the editor is on its right
+func EditorOffset() rl.Vector2 {
+	return rl.Vector2{X: 2 * layout.Square, Y: layout.Center.Y - engine.GridVerticalSize*layout.Square/2}
+}
+
+// InitEditor shows the board editor, keeping the board edited last
+func InitEditor() {
+	screen = EditorScreen
+	pause = false
+
+	if editorPuzzle.Title == "" {
+		editorPuzzle = engine.Puzzle{Title: "Custom board"}
+		editorGrid = engine.NewGrid()
+	}
+}
+
+// EditedPuzzle returns the edited board as a puzzle
+func EditedPuzzle() *engine.Puzzle {
+	p := editorPuzzle
+	p.Pieces = append([]string(nil), editorPuzzle.Pieces...)
+	p.Board = engine.TrimRows(engine.FormatRows(&editorGrid))
+
+	return &p
+}
+
+// UpdateEditor paints the squares under the mouse and handles the editor buttons
+func UpdateEditor() {
+	mouse := rl.GetMousePosition()
+	offset := EditorOffset()
+	i := int((mouse.X - offset.X) / layout.Square)
+	j := int((mouse.Y - offset.Y) / layout.Square)
+
+	if mouse.X >= offset.X && mouse.Y >= offset.Y && i >= 1 && i < engine.GridHorizontalSize-1 && j < engine.GridVerticalSize-1 {
+		if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			editorGrid[i][j] = engine.Full
+		} else if rl.IsMouseButtonDown(rl.MouseRightButton) {
+			editorGrid[i][j] = engine.Empty
+		}
+	}
+
+	if rl.IsKeyPressed(rl.KeyBackspace) && len(editorPuzzle.Pieces) > 0 {
+		editorPuzzle.Pieces = editorPuzzle.Pieces[:len(editorPuzzle.Pieces)-1]
+	}
+}
+
+// SaveEditor writes the edited board to the editor file
+func SaveEditor() {
+	if err := engine.SavePuzzle(editorPath, EditedPuzzle()); err != nil {
+		editorMessage = err.Error()
+		return
+	}
+	editorMessage = "SAVED TO " + editorPath
+}
+
+// LoadEditor replaces the edited board with the editor file
+func LoadEditor() {
+	p, err := engine.LoadPuzzle(editorPath, pieceSet)
+	if err != nil {
+		editorMessage = err.Error()
+		return
+	}
+
+	editorPuzzle = *p
+	editorGrid, _ = engine.ParseRows(p.Board)
+	editorMessage = "LOADED " + editorPath
+}
+
+// PlayEditor starts a game from the edited board
+func PlayEditor() {
+	editorPlaying = EditedPuzzle()
+	StartMode(editorPlaying)
+}
+
+// PlayingEditor reports whether the game on screen was started from the editor
+func PlayingEditor() bool {
+	p, ok := game.Mode.(*engine.Puzzle)
+	return ok && p == editorPlaying
+}
+
+// Button draws a button and reports whether it was clicked this frame, its width given for the window the layout was designed for
+func Button(text string, x, y, width float32) bool {
+	bounds := rl.Rectangle{X: x, Y: y, Width: layout.Size(width), Height: layout.Size(20)}
+	hover := rl.CheckCollisionPointRec(rl.GetMousePosition(), bounds)
+
+	color := rl.Gray
+	if hover {
+		color = rl.Maroon
+	}
+
+	rl.DrawRectangleLinesEx(bounds, 1, color)
+	size := layout.Text(10)
+	rl.DrawText(text, int32(x+bounds.Width/2)-rl.MeasureText(text, size)/2, int32(y+layout.Size(5)), size, color)
+
+	return hover && rl.IsMouseButtonPressed(rl.MouseLeftButton)
+}
+
+// DrawEditor draws the edited grid and the editor panel, acting on the buttons clicked
+func DrawEditor() {
+	rl.BeginDrawing()
+
+	rl.ClearBackground(rl.RayWhite)
+
+	offset := EditorOffset()
+	DrawGrid(&engine.Game{Grid: editorGrid}, offset)
+
+	// The panel lines up with the top of the grid
+	x := offset.X + engine.GridHorizontalSize*layout.Square + layout.Size(40)
+	y := offset.Y
+
+	rl.DrawText("BOARD EDITOR", int32(x), int32(y), layout.Text(20), rl.Black)
+	rl.DrawText("LEFT CLICK FILLS A SQUARE, RIGHT CLICK EMPTIES IT", int32(x), int32(y+layout.Size(30)), layout.Text(10), rl.Gray)
+
+	// Every piece of the set can be added to the queue
+	rl.DrawText("ADD TO THE QUEUE:", int32(x), int32(y+layout.Size(55)), layout.Text(10), rl.Gray)
+	for n, piece := range pieceSet.Pieces {
+		if Button(piece.Name, x+layout.Size(35*float32(n%12)), y+layout.Size(70+25*float32(n/12)), 30) && len(editorPuzzle.Pieces) < engine.MaxQueue {
+			editorPuzzle.Pieces = append(editorPuzzle.Pieces, piece.Name)
+		}
+	}
+
+	queue := strings.Join(editorPuzzle.Pieces, " ")
+	if queue == "" {
+		queue = "RANDOM PIECES"
+	}
+	rl.DrawText("QUEUE: "+queue, int32(x), int32(y+layout.Size(130)), layout.Text(10), rl.DarkGray)
+	rl.DrawText("[BACKSPACE] REMOVES THE LAST PIECE", int32(x), int32(y+layout.Size(145)), layout.Text(10), rl.Gray)
+	if Button("CLEAR QUEUE", x+layout.Size(250), y+layout.Size(140), 90) {
+		editorPuzzle.Pieces = nil
+	}
+
+	// Clicking the hold goes through every piece and back to nothing held
+	hold := editorPuzzle.Hold
+	if hold == "" {
+		hold = "NONE"
+	}
+	rl.DrawText("HOLD:", int32(x), int32(y+layout.Size(180)), layout.Text(10), rl.Gray)
+	if Button(hold, x+layout.Size(100), y+layout.Size(175), 60) {
+		next := pieceSet.Index(editorPuzzle.Hold) + 1
+		editorPuzzle.Hold = ""
+		if next < len(pieceSet.Pieces) {
+			editorPuzzle.Hold = pieceSet.Pieces[next].Name
+		}
+	}
+
+	rl.DrawText(fmt.Sprintf("LINES TO CLEAR: %d", editorPuzzle.Goal.Lines), int32(x), int32(y+layout.Size(210)), layout.Text(10), rl.Gray)
+	if Button("-", x+layout.Size(100), y+layout.Size(205), 25) {
+		editorPuzzle.Goal.Lines = max(editorPuzzle.Goal.Lines-1, 0)
+	}
+	if Button("+", x+layout.Size(135), y+layout.Size(205), 25) {
+		editorPuzzle.Goal.Lines++
+	}
+	rl.DrawText(FormatGoal(editorPuzzle.Goal), int32(x), int32(y+layout.Size(235)), layout.Text(10), rl.DarkGray)
+
+	if Button("EMPTY GRID", x, y+layout.Size(265), 90) {
+		editorGrid = engine.NewGrid()
+	}
+	if Button("SAVE", x+layout.Size(100), y+layout.Size(265), 60) {
+		SaveEditor()
+	}
+	if Button("LOAD", x+layout.Size(170), y+layout.Size(265), 60) {
+		LoadEditor()
+	}
+	if Button("BACK", x+layout.Size(240), y+layout.Size(265), 60) {
+		InitTitle()
+	}
+	if Button("PLAY FROM HERE", x, y+layout.Size(295), 300) {
+		PlayEditor()
+	}
+
+	rl.DrawText("FILE: "+editorPath, int32(x), int32(y+layout.Size(335)), layout.Text(10), rl.Gray)
+	rl.DrawText(editorMessage, int32(x), int32(y+layout.Size(350)), layout.Text(10), rl.Maroon)
+
+	rl.EndDrawing()
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+)
+
+// EffectsConfig picks the animations shown when lines are cleared
+type EffectsConfig struct {
+	Flash         bool // Cleared rows flash in the colour of the clear
+	Particles     bool // The squares of cleared rows shatter
+	Shake         bool // The grid shakes on a tetris
+	Score         bool // The points of a clear float up from the rows
+	ReducedMotion bool // Nothing moves or blinks: no particles or shaking, steady flashes and text
+}
+
+// effectsConfig is the effects configuration of every game
+var effectsConfig = EffectsConfig{Flash: true, Particles: true, Shake: true, Score: true}
+
+// gameEffects are the effects of the single player game
+var gameEffects Effects
+
+// effectNames lists the effects -effects can turn on
+var effectNames = []string{"flash", "particles", "shake", "score"}
+
+// ParseEffects reads a comma separated list of effect names, "none" turning all of them off
+func ParseEffects(list string) (EffectsConfig, error) {
+	var config EffectsConfig
+
+	for _, name := range strings.Split(list, ",") {
+		switch strings.TrimSpace(name) {
+		case "flash":
+			config.Flash = true
+		case "particles":
+			config.Particles = true
+		case "shake":
+			config.Shake = true
+		case "score":
+			config.Score = true
+		case "none", "":
+		default:
+			return config, fmt.Errorf("unknown effect %q, pick from %s", name, strings.Join(effectNames, ", "))
+		}
+	}
+
+	return config, nil
+}
+
+// Effect timings in seconds, and sizes in squares
+const (
+	MinFlashTime = 0.2 // Flashes last as long as the line clear delay, or this long when it is shorter
+	ParticleTime = 0.8
+	ShakeTime    = 0.35
+	ScoreTime    = 1.2
+	ShakeSize    = 0.4
+	ScoreRise    = 3
+)
+
+// clearColors is the colour of the effects of a clear of 1 to MaxPieceSize lines at once
+var clearColors = [engine.MaxPieceSize + 1]rl.Color{rl.White, rl.White, rl.SkyBlue, rl.Gold, rl.Orange, rl.Magenta}
+
+// Effects are the animations of one game, drawn over its grid. Positions
+// are in squares of the grid so the same effects follow the grid wherever
+// it is drawn.
+type Effects struct {
+	flashes   []flash
+	particles []particle
+	texts     []floatingText
+	shake     float32 // Seconds of shaking left
+
+	// State of the game when last seen
+	frame    int
+	deleting bool
+	score    int
+	rows     []int
+}
+
+type flash struct {
+	row   int
+	color rl.Color
+	age   float32
+	time  float32
+}
+
+type particle struct {
+	x, y   float32
+	vx, vy float32
+	color  rl.Color
+	age    float32
+}
+
+type floatingText struct {
+	text  string
+	x, y  float32
+	color rl.Color
+	age   float32
+}
+
+// Observe starts the effects of whatever happened in the last step of a game
+func (e *Effects) Observe(g *engine.Game) {
+	// A new game starts without the effects of the last one
+	if g.Frame < e.frame {
+		*e = Effects{}
+	}
+	e.frame = g.Frame
+
+	if g.LineToDelete && !e.deleting {
+		e.rows = e.rows[:0]
+		for j := 0; j < engine.GridVerticalSize-1; j++ {
+			if g.Grid[1][j] == engine.Fading {
+				e.rows = append(e.rows, j)
+			}
+		}
+		e.startClear(len(e.rows), max(float32(g.Timing.LineClearDelay)/engine.FrameRate, MinFlashTime))
+	}
+	e.deleting = g.LineToDelete
+
+	if g.Score > e.score && len(e.rows) > 0 && effectsConfig.Score {
+		lines := min(len(e.rows), engine.MaxPieceSize)
+		e.texts = append(e.texts, floatingText{
+			text:  fmt.Sprintf("%s +%d", ClearNames[lines], g.Score-e.score),
+			x:     engine.GridHorizontalSize / 2,
+			y:     float32(e.rows[len(e.rows)/2]),
+			color: clearColors[lines],
+		})
+		e.rows = e.rows[:0]
+	}
+	e.score = g.Score
+}
+
+// startClear starts the flash, the shattering and the shaking of the rows being cleared
+func (e *Effects) startClear(lines int, flashTime float32) {
+	color := clearColors[min(lines, engine.MaxPieceSize)]
+
+	for _, j := range e.rows {
+		if effectsConfig.Flash {
+			e.flashes = append(e.flashes, flash{row: j, color: color, time: flashTime})
+		}
+
+		if effectsConfig.Particles && !effectsConfig.ReducedMotion {
+			for i := 1; i < engine.GridHorizontalSize-1; i++ {
+				// More lines, more debris
+				for n := 0; n < min(lines, 4); n++ {
+					e.particles = append(e.particles, particle{
+						x:     float32(i) + rand.Float32(),
+						y:     float32(j) + rand.Float32(),
+						vx:    (rand.Float32() - 0.5) * 12,
+						vy:    -rand.Float32() * 10,
+						color: color,
+					})
+				}
+			}
+		}
+	}
+
+	if lines >= 4 && effectsConfig.Shake && !effectsConfig.ReducedMotion {
+		e.shake = ShakeTime
+	}
+}
+
+// Update moves the effects on by the time since the last drawn frame
+func (e *Effects) Update(dt float32) {
+	for n := range e.flashes {
+		e.flashes[n].age += dt
+	}
+	e.flashes = expire(e.flashes, func(f flash) bool { return f.age >= f.time })
+
+	for n := range e.particles {
+		p := &e.particles[n]
+		p.age += dt
+		p.vy += 30 * dt
+		p.x += p.vx * dt
+		p.y += p.vy * dt
+	}
+	e.particles = expire(e.particles, func(p particle) bool { return p.age >= ParticleTime })
+
+	for n := range e.texts {
+		e.texts[n].age += dt
+	}
+	e.texts = expire(e.texts, func(t floatingText) bool { return t.age >= ScoreTime })
+
+	e.shake = max(e.shake-dt, 0)
+}
+
+// expire removes the effects that are over
+func expire[T any](effects []T, over func(T) bool) []T {
+	kept := effects[:0]
+	for _, effect := range effects {
+		if !over(effect) {
+			kept = append(kept, effect)
+		}
+	}
+	return kept
+}
+
+// Shake returns how far to move the grid this frame, fading out as the shaking ends
+func (e *Effects) Shake() rl.Vector2 {
+	if e.shake <= 0 {
+		return rl.Vector2{}
+	}
+
+	size := ShakeSize * layout.Square * e.shake / ShakeTime
+	return rl.Vector2{X: (rand.Float32()*2 - 1) * size, Y: (rand.Float32()*2 - 1) * size}
+}
+
+// Draw draws the effects over a grid drawn with its top left corner at offset
+func (e *Effects) Draw(offset rl.Vector2) {
+	for _, f := range e.flashes {
+		// A steady highlight with reduced motion, a flash fading out otherwise
+		alpha := float32(0.5)
+		if !effectsConfig.ReducedMotion {
+			alpha = 1 - f.age/f.time
+		}
+		rl.DrawRectangleV(rl.Vector2{X: offset.X + layout.Square, Y: offset.Y + float32(f.row)*layout.Square}, rl.Vector2{X: (engine.GridHorizontalSize - 2) * layout.Square, Y: layout.Square}, rl.Fade(f.color, alpha))
+	}
+
+	for _, p := range e.particles {
+		size := layout.Square / 2 * (1 - p.age/ParticleTime)
+		rl.DrawRectangleV(rl.Vector2{X: offset.X + p.x*layout.Square - size/2, Y: offset.Y + p.y*layout.Square - size/2}, rl.Vector2{X: size, Y: size}, rl.Fade(p.color, 1-p.age/ParticleTime))
+	}
+
+	for _, t := range e.texts {
+		y := t.y
+		if !effectsConfig.ReducedMotion {
+			y -= ScoreRise * t.age / ScoreTime
+		}
+
+		size := layout.Text(20)
+		x := int32(offset.X+t.x*layout.Square) - rl.MeasureText(t.text, size)/2
+		alpha := 1 - t.age/ScoreTime
+		rl.DrawText(t.text, x+1, int32(offset.Y+y*layout.Square)+1, size, rl.Fade(rl.Black, alpha))
+		rl.DrawText(t.text, x, int32(offset.Y+y*layout.Square), size, rl.Fade(t.color, alpha))
+	}
+}
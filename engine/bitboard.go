@@ -0,0 +1,156 @@
+package engine
+
+import "math/bits"
+
+// Bitboard is the playfield as one bitmask per row, bit i set when square i
+// of the row is taken (walls and floor included). Testing a piece against it
+// takes one AND per row of the piece instead of a look at every square.
+type Bitboard [GridVerticalSize]uint16
+
+// Row masks of a bitboard
+const (
+	WallsRow    uint16 = 1 | 1<<(GridHorizontalSize-1) // A row with nothing between the walls
+	FullRow     uint16 = 1<<GridHorizontalSize - 1     // A row taken from wall to wall
+	InteriorRow        = FullRow &^ WallsRow           // The squares between the walls
+)
+
+const (
+	floorRow   = GridVerticalSize - 1 // Index of the floor row
+	outsideRow = ^uint32(FullRow)     // Bits past the right wall once a row is shifted
+)
+
+// PieceMask is one rotation state of a piece as one bitmask per row of its box
+type PieceMask [MaxPieceSize]uint16
+
+// Mask returns the rows of a shape as bitmasks
+func (s *PieceShape) Mask() PieceMask {
+	var mask PieceMask
+
+	for i := 0; i < MaxPieceSize; i++ {
+		for j := 0; j < MaxPieceSize; j++ {
+			if s[i][j] == Moving {
+				mask[j] |= 1 << i
+			}
+		}
+	}
+
+	return mask
+}
+
+// NewBitboard returns the squares of a grid that are not empty
+func NewBitboard(grid *Grid) Bitboard {
+	var b Bitboard
+
+	for i := 0; i < GridHorizontalSize; i++ {
+		for j := 0; j < GridVerticalSize; j++ {
+			if grid[i][j] != Empty {
+				b[j] |= 1 << i
+			}
+		}
+	}
+
+	return b
+}
+
+// shift moves a row of a piece mask to column x, false when part of it ends up outside the grid
+func shift(row uint16, x int) (uint16, bool) {
+	if x < 0 {
+		if row&(1<<-x-1) != 0 {
+			return 0, false
+		}
+		return row >> -x, true
+	}
+
+	shifted := uint32(row) << x
+	return uint16(shifted), shifted&outsideRow == 0
+}
+
+// Fits reports whether a piece with the top left corner of its box at x, y
+// is inside the grid and only covers empty squares
+func (b *Bitboard) Fits(mask *PieceMask, x, y int) bool {
+	for j, row := range mask {
+		if row == 0 {
+			continue
+		}
+		if y+j < 0 || y+j >= GridVerticalSize {
+			return false
+		}
+
+		shifted, ok := shift(row, x)
+		if !ok || b[y+j]&shifted != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Drop returns the row a piece that fits at x, y falls to
+func (b *Bitboard) Drop(mask *PieceMask, x, y int) int {
+	for b.Fits(mask, x, y+1) {
+		y++
+	}
+	return y
+}
+
+// Put takes the squares of a piece, which must be inside the grid
+func (b *Bitboard) Put(mask *PieceMask, x, y int) {
+	for j, row := range mask {
+		if row != 0 {
+			shifted, _ := shift(row, x)
+			b[y+j] |= shifted
+		}
+	}
+}
+
+// ClearLines removes the rows taken from wall to wall, pulling the rows above
+// down, and returns how many it removed
+func (b *Bitboard) ClearLines() int {
+	to := floorRow - 1
+	for from := floorRow - 1; from >= 0; from-- {
+		if b[from] == FullRow {
+			continue
+		}
+		b[to] = b[from]
+		to--
+	}
+
+	lines := to + 1
+	for ; to >= 0; to-- {
+		b[to] = WallsRow
+	}
+
+	return lines
+}
+
+// Heights returns the height of every column above the floor, 0 for the walls
+func (b *Bitboard) Heights() [GridHorizontalSize]int {
+	var heights [GridHorizontalSize]int
+
+	// Going down, a column is as high as the first row where it is taken
+	var seen uint16
+	for j := 0; j < floorRow; j++ {
+		fresh := b[j] & InteriorRow &^ seen
+		for fresh != 0 {
+			i := bits.TrailingZeros16(fresh)
+			heights[i] = floorRow - j
+			fresh &= fresh - 1
+		}
+		seen |= b[j]
+	}
+
+	return heights
+}
+
+// Holes returns the number of empty squares with a taken square somewhere above
+func (b *Bitboard) Holes() int {
+	holes := 0
+
+	var covered uint16
+	for j := 0; j < floorRow; j++ {
+		holes += bits.OnesCount16(covered &^ b[j] & InteriorRow)
+		covered |= b[j]
+	}
+
+	return holes
+}
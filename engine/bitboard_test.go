@@ -0,0 +1,144 @@
+package engine
+
+import "testing"
+
+// gridFits is the square by square test the bitboard replaces, kept to check it against
+func gridFits(grid *Grid, shape *PieceShape, x, y int) bool {
+	for i := 0; i < MaxPieceSize; i++ {
+		for j := 0; j < MaxPieceSize; j++ {
+			if shape[i][j] != Moving {
+				continue
+			}
+			if x+i < 0 || x+i >= GridHorizontalSize || y+j < 0 || y+j >= GridVerticalSize {
+				return false
+			}
+			if grid[x+i][y+j] != Empty {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// randomGrid returns a grid with its bottom rows filled at random, a few squares left empty in each
+func randomGrid(random *Random, height int) Grid {
+	grid := NewGrid()
+
+	for j := GridVerticalSize - 1 - height; j < GridVerticalSize-1; j++ {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			if random.Intn(4) != 0 {
+				grid[i][j] = Full
+			}
+		}
+	}
+
+	return grid
+}
+
+func TestBitboardMatchesGrid(t *testing.T) {
+	random := NewRandom(1)
+
+	for _, path := range []string{"", "../piecesets/pentominoes.json", "../piecesets/bigblock.json"} {
+		set := DefaultPieceSet()
+		if path != "" {
+			var err error
+			if set, err = LoadPieceSet(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for n := 0; n < 20; n++ {
+			grid := randomGrid(&random, random.Intn(GridVerticalSize-1))
+			board := NewBitboard(&grid)
+
+			for _, piece := range set.Pieces {
+				for r := range piece.Rotations {
+					for x := -MaxPieceSize; x < GridHorizontalSize+MaxPieceSize; x++ {
+						for y := -MaxPieceSize; y < GridVerticalSize+MaxPieceSize; y++ {
+							if got, want := board.Fits(&piece.Masks[r], x, y), gridFits(&grid, &piece.Rotations[r], x, y); got != want {
+								t.Fatalf("%s rotation %d at %d,%d: Fits is %v, want %v\n%s", piece.Name, r, x, y, got, want, FormatBoard(&grid))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBitboardPutAndDrop(t *testing.T) {
+	grid, _ := ParseBoard(".......#..")
+	board := NewBitboard(&grid)
+	set := DefaultPieceSet()
+	mask := &set.Pieces[set.Index("I")].Masks[0]
+
+	// The I piece lies in the second row of its box
+	y := board.Drop(mask, 5, 0)
+	if y != GridVerticalSize-4 {
+		t.Fatalf("dropped to %d, want %d", y, GridVerticalSize-4)
+	}
+
+	board.Put(mask, 5, y)
+	want, _ := ParseBoard("....####..\n.......#..")
+	if board != NewBitboard(&want) {
+		t.Errorf("board after the drop is %v, want %v", board, NewBitboard(&want))
+	}
+}
+
+func TestBitboardClearLines(t *testing.T) {
+	grid, _ := ParseBoard("#.........\n##########\n.#########\n##########\n##########")
+	board := NewBitboard(&grid)
+
+	if lines := board.ClearLines(); lines != 3 {
+		t.Errorf("cleared %d lines, want 3", lines)
+	}
+
+	want, _ := ParseBoard("#.........\n.#########")
+	if board != NewBitboard(&want) {
+		t.Errorf("board after clearing is %v, want %v", board, NewBitboard(&want))
+	}
+}
+
+func TestBitboardHeightsAndHoles(t *testing.T) {
+	grid, _ := ParseBoard("...#......\n#..#......\n#.##.....#\n.#.#....##")
+	board := NewBitboard(&grid)
+
+	want := [GridHorizontalSize]int{0, 3, 1, 2, 4, 0, 0, 0, 0, 1, 2, 0}
+	if heights := board.Heights(); heights != want {
+		t.Errorf("heights are %v, want %v", heights, want)
+	}
+	if holes := board.Holes(); holes != 2 {
+		t.Errorf("%d holes, want 2", holes)
+	}
+}
+
+func BenchmarkFits(b *testing.B) {
+	random := NewRandom(1)
+	grid := randomGrid(&random, 8)
+	board := NewBitboard(&grid)
+	set := DefaultPieceSet()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, piece := range set.Pieces {
+			for x := 0; x < GridHorizontalSize-2; x++ {
+				board.Fits(&piece.Masks[0], x, GridVerticalSize-8)
+			}
+		}
+	}
+}
+
+func BenchmarkGridFits(b *testing.B) {
+	random := NewRandom(1)
+	grid := randomGrid(&random, 8)
+	set := DefaultPieceSet()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, piece := range set.Pieces {
+			for x := 0; x < GridHorizontalSize-2; x++ {
+				gridFits(&grid, &piece.Rotations[0], x, GridVerticalSize-8)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Board notation: a grid is written as text rows of the squares inside the
+// walls, top to bottom, the last row resting on the floor. Rows left out at
+// the top are empty. Each kind of square has its own character, and letters
+// stand for Full squares too, so fixtures can mark which piece left them:
+//
+//	..........
+//	....@@@...
+//	.....@....
+//	~~~~~~~~~~
+//	###.IIII##
+//
+// The grid of a game never holds Moving squares: '@' shows the active piece
+// in a Board, and in test fixtures.
+//
+// FormatRows and FormatBoard only write the characters below, so reading a
+// formatted grid back gives the same grid as long as its walls and floor are
+// in place, and formatting a board read from text gives the same text unless
+// it used letters or empty rows at the top.
+const (
+	EmptyChar  = '.'
+	MovingChar = '@'
+	FullChar   = '#'
+	BlockChar  = '='
+	FadingChar = '~'
+)
+
+// squareChars maps each GridSquare to its character
+var squareChars = [...]byte{
+	Empty:  EmptyChar,
+	Moving: MovingChar,
+	Full:   FullChar,
+	Block:  BlockChar,
+	Fading: FadingChar,
+}
+
+// ParseSquare reads the character of a square, letters standing for Full squares
+func ParseSquare(c rune) (GridSquare, bool) {
+	for square, char := range squareChars {
+		if rune(char) == c {
+			return GridSquare(square), true
+		}
+	}
+
+	if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+		return Full, true
+	}
+	return Empty, false
+}
+
+// NewGrid returns an empty grid between its walls and floor
+func NewGrid() Grid {
+	var grid Grid
+
+	for i := 0; i < GridHorizontalSize; i++ {
+		for j := 0; j < GridVerticalSize; j++ {
+			if j == GridVerticalSize-1 || i == 0 || i == GridHorizontalSize-1 {
+				grid[i][j] = Block
+			} else {
+				grid[i][j] = Empty
+			}
+		}
+	}
+
+	return grid
+}
+
+// ParseRows reads the rows of a board, returning an empty grid along with the error when a row is malformed
+func ParseRows(rows []string) (Grid, error) {
+	grid := NewGrid()
+
+	if len(rows) > GridVerticalSize-1 {
+		return NewGrid(), fmt.Errorf("board has %d rows, at most %d fit", len(rows), GridVerticalSize-1)
+	}
+
+	top := GridVerticalSize - 1 - len(rows)
+	for j, row := range rows {
+		if len(row) != GridHorizontalSize-2 {
+			return NewGrid(), fmt.Errorf("board row %q is not %d squares wide", row, GridHorizontalSize-2)
+		}
+
+		for i, c := range row {
+			square, ok := ParseSquare(c)
+			if !ok {
+				return NewGrid(), fmt.Errorf("board row %q: unknown square %q", row, c)
+			}
+			grid[i+1][top+j] = square
+		}
+	}
+
+	return grid, nil
+}
+
+// FormatRows writes every row of a grid inside its walls
+func FormatRows(grid *Grid) []string {
+	rows := make([]string, GridVerticalSize-1)
+
+	for j := range rows {
+		row := make([]byte, GridHorizontalSize-2)
+		for i := range row {
+			row[i] = squareChars[grid[i+1][j]]
+		}
+		rows[j] = string(row)
+	}
+
+	return rows
+}
+
+// ParseBoard reads a board written one row per line. Blank lines and the
+// spaces around rows are left out, so boards can be indented in tests.
+func ParseBoard(text string) (Grid, error) {
+	var rows []string
+
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, line)
+		}
+	}
+
+	return ParseRows(rows)
+}
+
+// FormatBoard writes a grid one row per line, from its first row that is not empty
+func FormatBoard(grid *Grid) string {
+	return strings.Join(TrimRows(FormatRows(grid)), "\n")
+}
+
+// TrimRows leaves out the empty rows at the top of a board
+func TrimRows(rows []string) []string {
+	empty := strings.Repeat(string(EmptyChar), GridHorizontalSize-2)
+	for len(rows) > 0 && rows[0] == empty {
+		rows = rows[1:]
+	}
+	return rows
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBoard(t *testing.T) {
+	grid, err := ParseBoard(`
+		....@@@...
+		.....@....
+		~~~~~~~~~~
+		###.IIII##
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		x, y int
+		want GridSquare
+	}{
+		{1, 0, Empty},
+		{5, GridVerticalSize - 5, Moving},
+		{6, GridVerticalSize - 4, Moving},
+		{1, GridVerticalSize - 3, Fading},
+		{3, GridVerticalSize - 2, Full},
+		{4, GridVerticalSize - 2, Empty},
+		{5, GridVerticalSize - 2, Full}, // Letters are full squares
+		{0, 5, Block},
+		{GridHorizontalSize - 1, 5, Block},
+		{4, GridVerticalSize - 1, Block},
+	}
+	for _, test := range tests {
+		if got := grid[test.x][test.y]; got != test.want {
+			t.Errorf("square %d,%d is %d, want %d", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+	tests := map[string]string{
+		"narrow row":   "#########",
+		"wide row":     "###########",
+		"unknown":      "#####?####",
+		"digit":        "#####1####",
+		"too tall":     strings.Repeat("..........\n", GridVerticalSize),
+		"narrow later": "..........\n...",
+	}
+	for name, text := range tests {
+		grid, err := ParseBoard(text)
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if grid != NewGrid() {
+			t.Errorf("%s: grid is not empty", name)
+		}
+	}
+}
+
+func TestFormatBoard(t *testing.T) {
+	boards := []string{
+		"",
+		"#########.",
+		"=........=\n@@@@......\n~~~~~~~~~~\n#.#.#.#.#.",
+		strings.TrimSuffix(strings.Repeat("##########\n", GridVerticalSize-1), "\n"),
+	}
+	for _, board := range boards {
+		grid, err := ParseBoard(board)
+		if err != nil {
+			t.Fatalf("%q: %v", board, err)
+		}
+		if got := FormatBoard(&grid); got != board {
+			t.Errorf("board %q formats as %q", board, got)
+		}
+	}
+}
+
+func TestFormatRows(t *testing.T) {
+	g := NewGame(DefaultPieceSet(), 1)
+	for i := 0; i < 200; i++ {
+		g.Step(InputDown)
+	}
+
+	rows := FormatRows(&g.Grid)
+	if len(rows) != GridVerticalSize-1 {
+		t.Fatalf("got %d rows, want %d", len(rows), GridVerticalSize-1)
+	}
+
+	grid, err := ParseRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid != g.Grid {
+		t.Errorf("rows read back as another grid:\n%s\nwant\n%s", FormatBoard(&grid), FormatBoard(&g.Grid))
+	}
+	if !slices.Equal(TrimRows(rows), strings.Split(FormatBoard(&g.Grid), "\n")) {
+		t.Errorf("FormatBoard does not match the trimmed rows")
+	}
+}
+
+func FuzzBoard(f *testing.F) {
+	f.Add("#########.")
+	f.Add("@@@@......\n~~~~~~~~~~\n#.#.#.#.#.")
+	f.Add("ABCDEFGHIJ\n  ==========  ")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		grid, err := ParseBoard(text)
+		if err != nil {
+			return
+		}
+
+		// Formatting is canonical: the grid reads back the same, and formats the same again
+		board := FormatBoard(&grid)
+		again, err := ParseBoard(board)
+		if err != nil {
+			t.Fatalf("formatted board %q does not parse: %v", board, err)
+		}
+		if again != grid {
+			t.Fatalf("board %q reads back as another grid", board)
+		}
+		if FormatBoard(&again) != board {
+			t.Fatalf("board %q formats differently the second time", board)
+		}
+	})
+}
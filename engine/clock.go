@@ -0,0 +1,32 @@
+package engine
+
+import "time"
+
+// FrameRate is the number of steps in a second of play. Every frame counter
+// of the engine (gravity, movement, fading, countdowns) is written for it.
+const FrameRate = 60
+
+// StepTime is the real time one step stands for
+const StepTime = time.Second / FrameRate
+
+// MaxCatchUp is the most steps a Clock hands out at once. After a longer
+// stall the game slows down instead of jumping ahead.
+const MaxCatchUp = 8
+
+// Clock runs the game at FrameRate whatever the rate frames are drawn at: it
+// adds up the real time between drawn frames and hands it out as whole
+// steps, carrying the remainder over to the next frame.
+type Clock struct {
+	accumulated time.Duration
+}
+
+// Advance adds the time since the last drawn frame and returns how many steps to run
+func (c *Clock) Advance(elapsed time.Duration) int {
+	c.accumulated += max(elapsed, 0)
+	c.accumulated = min(c.accumulated, MaxCatchUp*StepTime)
+
+	steps := int(c.accumulated / StepTime)
+	c.accumulated -= time.Duration(steps) * StepTime
+
+	return steps
+}
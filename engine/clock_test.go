@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame time.Duration // Real time between two drawn frames
+		steps int           // Steps over a second of frames
+	}{
+		{"60Hz", time.Second / 60, FrameRate},
+		{"144Hz", time.Second / 144, FrameRate},
+		{"30Hz", time.Second / 30, FrameRate},
+		{"uneven", 23 * time.Millisecond, FrameRate},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var clock Clock
+			steps := 0
+			for elapsed := time.Duration(0); elapsed+test.frame <= time.Second; elapsed += test.frame {
+				steps += clock.Advance(test.frame)
+			}
+			// Whatever was left of the second after the last frame is carried over
+			steps += clock.Advance(time.Second % test.frame)
+
+			if steps < test.steps-1 || steps > test.steps {
+				t.Errorf("%d steps in a second, want %d", steps, test.steps)
+			}
+		})
+	}
+}
+
+func TestClockCatchUp(t *testing.T) {
+	var clock Clock
+
+	if steps := clock.Advance(3 * StepTime); steps != 3 {
+		t.Errorf("%d steps after a dropped frame, want 3", steps)
+	}
+	if steps := clock.Advance(5 * time.Second); steps != MaxCatchUp {
+		t.Errorf("%d steps after a stall, want %d", steps, MaxCatchUp)
+	}
+	if steps := clock.Advance(StepTime / 2); steps != 0 {
+		t.Errorf("%d steps after a stall and half a step, want the stall dropped", steps)
+	}
+	if steps := clock.Advance(-time.Second); steps != 0 {
+		t.Errorf("%d steps for a clock going back", steps)
+	}
+}
@@ -0,0 +1,552 @@
+// Package engine holds the rules of the game, free of any rendering or
+// input library so it can run headless for bots, tools and tests.
+package engine
+
+// Some Defines
+const (
+	GridHorizontalSize   = 12
+	GridVerticalSize     = 20
+	LateralSpeed         = 10
+	TurningSpeed         = 12
+	FastFallAwaitCounter = 30
+	FadingTime           = 33 // Line clear delay of ClassicTiming
+	GravitySpeedInitial  = 30
+)
+
+// MaxQueue is how many pieces can be queued to be dealt in order
+const MaxQueue = 32
+
+// GridSquare represents the state of a square in the grid
+type GridSquare int
+
+// Enumeration for GridSquare
+const (
+	Empty GridSquare = iota
+	Moving
+	Full
+	Block
+	Fading
+)
+
+// LineScores is the score of clearing 0 to MaxPieceSize lines at once
+var LineScores = [MaxPieceSize + 1]int{0, 100, 300, 500, 800, 1200}
+
+// Grid is the playfield indexed [x][y], walls and floor included. The
+// active piece is not part of it until it locks; Board draws it in.
+type Grid [GridHorizontalSize][GridVerticalSize]GridSquare
+
+// Input is the set of buttons held down during one frame
+type Input uint8
+
+// Buttons making up an Input
+const (
+	InputLeft Input = 1 << iota
+	InputRight
+	InputRotate
+	InputDown
+	InputHold
+)
+
+// Game is the state of one game. Every field is a plain value apart from
+// the shared, read-only piece set, so copying a Game snapshots it.
+type Game struct {
+	Grid Grid // Replace it with SetGrid, which keeps the bitboard in step
+	Set  *PieceSet
+
+	PieceType      int // The active piece is this rotation of the type with the top left corner of its box at the position
+	PieceRotation  int
+	PiecePositionX int
+	PiecePositionY int
+	PieceActive    bool
+	IncomingType   int // -1 once a limited queue has run out
+	HoldType       int // -1 while nothing is held
+	HoldUsed       bool
+	Spawned        int           // Pieces spawned so far, holding included
+	Pieces         int           // Pieces locked so far
+	Queue          [MaxQueue]int // Pieces dealt in order before any random one
+	QueueLength    int           // Entries of Queue in use
+	QueueOnly      bool          // No random pieces once the queue has run out
+
+	GameOver        bool // Topped out, or failed the goal of the mode
+	Finished        bool // Reached the goal of the mode
+	LineToDelete    bool
+	Lines           int
+	Score           int
+	FadeLineCounter int
+	Frame           int
+	Level           int                   // Speed level, 0 when the mode has none
+	Countdown       int                   // Frames left before the game starts
+	Clears          [MaxPieceSize + 1]int // Line clears by number of lines cleared at once
+
+	Garbage        [MaxPendingGarbage]Garbage // Attacks waiting to enter the grid, oldest first
+	PendingGarbage int                        // Entries of Garbage in use
+	LinesSent      int                        // Garbage rows sent to opponents after cancelling
+	GarbageLines   int                        // Garbage rows at the bottom of the grid not cleared yet
+	GarbageAdded   int                        // Garbage rows that ever entered the grid
+
+	Mode       Mode           // Rules deciding when the game is finished, nil for endless play
+	Timing     Timing         // Delays of the game, ClassicTiming unless changed before the first step
+	Splits     [MaxSplits]int // Frames at which the milestones of the mode were reached
+	SplitCount int            // Entries of Splits in use
+
+	detection               bool
+	gravityMovementCounter  int
+	lateralMovementCounter  int
+	turnMovementCounter     int
+	fastFallMovementCounter int
+	gravitySpeed            int
+	spawnDelay              int      // Frames left before the next piece spawns
+	shiftFrames             int      // Frames the sideways button has been held
+	lockFrames              int      // Frames the active piece has rested on the stack
+	bits                    Bitboard // Taken squares of the grid, for collisions
+
+	input    Input
+	previous Input
+	random   Random
+}
+
+// NewGame initializes a game playing the given piece set. The same seed and
+// the same inputs always give the same game.
+func NewGame(set *PieceSet, seed int64) *Game {
+	g := &Game{
+		Set:          set,
+		HoldType:     -1,
+		Timing:       ClassicTiming,
+		gravitySpeed: GravitySpeedInitial,
+		random:       NewRandom(seed),
+	}
+
+	// Initialize grid matrices
+	g.SetGrid(NewGrid())
+
+	g.GetRandomPiece()
+
+	return g
+}
+
+// NewModeGame initializes a game following the rules of a mode
+func NewModeGame(set *PieceSet, seed int64, mode Mode) *Game {
+	g := NewGame(set, seed)
+	g.Mode = mode
+	mode.Setup(g)
+
+	return g
+}
+
+// SetGrid replaces the playfield
+func (g *Game) SetGrid(grid Grid) {
+	g.Grid = grid
+	g.bits = NewBitboard(&grid)
+}
+
+// Bits returns the taken squares of the grid as a bitboard
+func (g *Game) Bits() Bitboard {
+	return g.bits
+}
+
+// Step updates the game logic for one frame with the buttons held in that frame
+func (g *Game) Step(input Input) {
+	g.previous, g.input = g.input, input
+
+	if g.GameOver || g.Finished {
+		return
+	}
+
+	// Nothing moves and the clock does not run until the countdown is over
+	if g.Countdown > 0 {
+		g.Countdown--
+		return
+	}
+
+	g.Frame++
+	g.tickGarbage()
+
+	// Holding a sideways button charges the automatic shift, even between pieces
+	if g.down(InputLeft) || g.down(InputRight) {
+		g.shiftFrames++
+	}
+	if g.pressed(InputLeft) || g.pressed(InputRight) || !g.down(InputLeft|InputRight) {
+		g.shiftFrames = 0
+	}
+
+	if !g.LineToDelete {
+		if !g.PieceActive && g.spawnDelay > 0 {
+			// Entry delay before the next piece
+			g.spawnDelay--
+		} else if !g.PieceActive {
+			// Get another piece
+			g.PieceActive = g.CreatePiece()
+
+			// We leave a little time before starting the fast falling down
+			g.fastFallMovementCounter = 0
+		} else if g.pressed(InputHold) && !g.HoldUsed && (g.HoldType >= 0 || g.IncomingType >= 0) {
+			g.HoldPiece()
+		} else { // Piece falling
+			// Counters update
+			g.fastFallMovementCounter++
+			g.gravityMovementCounter++
+			g.lateralMovementCounter++
+			g.turnMovementCounter++
+
+			// We make sure to move if we've pressed the key this frame
+			if g.pressed(InputLeft) || g.pressed(InputRight) {
+				g.lateralMovementCounter = LateralSpeed
+			}
+			if g.pressed(InputRotate) {
+				g.turnMovementCounter = TurningSpeed
+			}
+
+			// Fall down
+			if g.down(InputDown) && (g.fastFallMovementCounter >= FastFallAwaitCounter) {
+				// We make sure the piece is going to fall this frame
+				g.gravityMovementCounter += g.gravitySpeed
+			}
+
+			if g.gravityMovementCounter >= g.gravitySpeed {
+				// Basic falling movement
+				g.CheckDetection()
+
+				// Check if the piece has collided with another piece or with the boundings
+				g.ResolveFallingMovement()
+
+				// Check if we fulfilled a line and if so, erase the line and pull down the lines above
+				g.CheckCompletion()
+
+				g.gravityMovementCounter = 0
+			}
+
+			// Move laterally at player's will, unless the piece has just locked
+			if g.PieceActive && g.lateralMovementCounter >= LateralSpeed {
+				// Update the lateral movement and if success, reset the lateral counter
+				if g.ResolveLateralMovement() {
+					g.lateralMovementCounter = 0
+				}
+			}
+
+			// Turn the piece at player's will, unless the piece has just locked
+			if g.PieceActive && g.turnMovementCounter >= TurningSpeed {
+				// Update the turning movement and reset the turning counter
+				if g.ResolveTurnMovement() {
+					g.turnMovementCounter = 0
+				}
+			}
+
+			// A piece resting on the stack locks once the lock delay has run out
+			if g.PieceActive && g.Timing.LockDelay > 0 {
+				g.ResolveLockDelay()
+			}
+		}
+
+		// Game over logic
+		if (g.bits[0]|g.bits[1])&InteriorRow != 0 {
+			g.GameOver = true
+		}
+	} else {
+		// Animation when deleting lines
+		g.FadeLineCounter++
+
+		if g.FadeLineCounter >= g.Timing.LineClearDelay {
+			deletedLines := g.DeleteCompleteLines()
+			g.FadeLineCounter = 0
+			g.LineToDelete = false
+			g.spawnDelay = g.Timing.LineARE
+
+			g.Lines += deletedLines
+			g.Score += LineScores[min(deletedLines, MaxPieceSize)]
+			g.Clears[min(deletedLines, MaxPieceSize)]++
+			g.sendGarbage(deletedLines)
+		}
+	}
+
+	if g.Mode != nil {
+		g.Mode.Update(g)
+	}
+}
+
+// pressed reports whether a button went down this frame
+func (g *Game) pressed(button Input) bool {
+	return g.input&button != 0 && g.previous&button == 0
+}
+
+// down reports whether a button is held this frame
+func (g *Game) down(button Input) bool {
+	return g.input&button != 0
+}
+
+// CreatePiece takes the incoming piece, places it at the top of the grid and picks the next incoming one
+func (g *Game) CreatePiece() bool {
+	// Garbage comes in between pieces
+	g.enterGarbage()
+
+	// Once the queue has run out only the held piece is left
+	if g.IncomingType < 0 {
+		if g.HoldType < 0 {
+			return false
+		}
+
+		g.SpawnPiece(g.HoldType)
+		g.HoldType = -1
+		return true
+	}
+
+	g.SpawnPiece(g.IncomingType)
+
+	// We assign a random piece to the incoming one
+	g.GetRandomPiece()
+
+	return true
+}
+
+// SpawnPiece places a piece of the given type at the top of the grid. The
+// game is over when there is no room for it.
+func (g *Game) SpawnPiece(pieceType int) {
+	x := (GridHorizontalSize - g.Set.Pieces[pieceType].Size) / 2
+
+	if !g.CanPlace(pieceType, 0, x, 0) {
+		g.GameOver = true
+		return
+	}
+
+	g.PieceType = pieceType
+	g.PieceRotation = 0
+	g.PiecePositionX = x
+	g.PiecePositionY = 0
+	g.lockFrames = 0
+
+	g.Spawned++
+}
+
+// GetRandomPiece picks the incoming piece: the first one of the queue, else
+// a random piece from the set following the piece weights
+func (g *Game) GetRandomPiece() {
+	if g.QueueLength > 0 {
+		g.IncomingType = g.Queue[0]
+		copy(g.Queue[:], g.Queue[1:g.QueueLength])
+		g.QueueLength--
+		return
+	}
+
+	if g.QueueOnly {
+		g.IncomingType = -1
+		return
+	}
+
+	g.IncomingType = g.Set.Pick(g.random.Intn(g.Set.TotalWeight()))
+}
+
+// HoldPiece puts the active piece on hold and brings in the one held before,
+// or the incoming piece when nothing was held yet. Only once per piece.
+func (g *Game) HoldPiece() {
+	held := g.HoldType
+	g.HoldType = g.PieceType
+
+	if held < 0 {
+		g.CreatePiece()
+	} else {
+		g.SpawnPiece(held)
+	}
+
+	g.HoldUsed = true
+	g.fastFallMovementCounter = 0
+}
+
+// CanPlace reports whether a piece fits in a rotation with the top left
+// corner of its box at x, y: every square of the piece inside the grid, on
+// an empty square.
+func (g *Game) CanPlace(pieceType, rotation, x, y int) bool {
+	if pieceType < 0 || pieceType >= len(g.Set.Pieces) {
+		return false
+	}
+
+	if rotation < 0 || rotation >= len(g.Set.Pieces[pieceType].Masks) {
+		return false
+	}
+
+	return g.bits.Fits(&g.Set.Pieces[pieceType].Masks[rotation], x, y)
+}
+
+// Place moves the active piece to a rotation and position if it fits there,
+// returning false and leaving it where it was otherwise.
+func (g *Game) Place(rotation, x, y int) bool {
+	if !g.CanPlace(g.PieceType, rotation, x, y) {
+		return false
+	}
+
+	g.PieceRotation = rotation
+	g.PiecePositionX = x
+	g.PiecePositionY = y
+
+	return true
+}
+
+// Shape returns the shape of the active piece in its rotation
+func (g *Game) Shape() *PieceShape {
+	return &g.Set.Pieces[g.PieceType].Rotations[g.PieceRotation]
+}
+
+// Board returns the grid as it is shown, with the active piece drawn in as Moving squares
+func (g *Game) Board() Grid {
+	board := g.Grid
+	if g.PieceActive {
+		g.stamp(&board, Moving)
+	}
+	return board
+}
+
+// stamp writes a square in a grid everywhere the active piece is
+func (g *Game) stamp(grid *Grid, square GridSquare) {
+	shape := g.Shape()
+
+	for i := 0; i < MaxPieceSize; i++ {
+		for j := 0; j < MaxPieceSize; j++ {
+			x, y := g.PiecePositionX+i, g.PiecePositionY+j
+
+			if shape[i][j] == Moving && x >= 0 && x < GridHorizontalSize && y >= 0 && y < GridVerticalSize {
+				grid[x][y] = square
+			}
+		}
+	}
+}
+
+// ResolveFallingMovement checks if the current piece should stop moving (if it has landed) or continue falling.
+func (g *Game) ResolveFallingMovement() {
+	if g.detection {
+		g.detection = false
+
+		// With a lock delay the piece stays until ResolveLockDelay locks it
+		if g.Timing.LockDelay == 0 {
+			g.lock()
+		}
+	} else {
+		// We move down the piece
+		g.Place(g.PieceRotation, g.PiecePositionX, g.PiecePositionY+1)
+	}
+}
+
+// ResolveLockDelay counts the frames the active piece rests on the stack,
+// locking it once they reach the lock delay. A piece that can fall again
+// starts counting over.
+func (g *Game) ResolveLockDelay() {
+	if g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY+1) {
+		g.lockFrames = 0
+		return
+	}
+
+	if g.lockFrames >= g.Timing.LockDelay {
+		g.lock()
+		g.CheckCompletion()
+		return
+	}
+	g.lockFrames++
+}
+
+// lock turns the active piece into full squares of the grid and starts the entry delay of the next one
+func (g *Game) lock() {
+	g.stamp(&g.Grid, Full)
+	g.bits.Put(&g.Set.Pieces[g.PieceType].Masks[g.PieceRotation], g.PiecePositionX, g.PiecePositionY)
+	g.PieceActive = false
+	g.spawnDelay = g.Timing.ARE
+
+	g.HoldUsed = false
+	g.Pieces++
+}
+
+// ResolveLateralMovement checks and performs lateral movement of the current piece, returning true if a collision occurs.
+func (g *Game) ResolveLateralMovement() bool {
+	if g.pressed(InputLeft) || g.autoShift(InputLeft) { // Move left
+		return !g.Place(g.PieceRotation, g.PiecePositionX-1, g.PiecePositionY)
+	} else if g.pressed(InputRight) || g.autoShift(InputRight) { // Move right
+		return !g.Place(g.PieceRotation, g.PiecePositionX+1, g.PiecePositionY)
+	}
+
+	return false
+}
+
+// autoShift reports whether a held sideways button moves the piece on its
+// own this frame: once it has been held DAS frames, then every ARR frames
+func (g *Game) autoShift(button Input) bool {
+	if g.Timing.DAS == 0 || !g.down(button) || g.shiftFrames < g.Timing.DAS {
+		return false
+	}
+	return (g.shiftFrames-g.Timing.DAS)%max(g.Timing.ARR, 1) == 0
+}
+
+// ResolveTurnMovement checks if the rotate button is held and rotates the piece if possible.
+func (g *Game) ResolveTurnMovement() bool {
+	// Input for turning the piece
+	if g.down(InputRotate) {
+		rotation := (g.PieceRotation + 1) % len(g.Set.Pieces[g.PieceType].Rotations)
+		g.Place(rotation, g.PiecePositionX, g.PiecePositionY)
+
+		return true
+	}
+
+	return false
+}
+
+// CheckDetection checks whether the moving piece rests on a full square or the bottom of the grid.
+func (g *Game) CheckDetection() {
+	if !g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY+1) {
+		g.detection = true
+	}
+}
+
+// CheckCompletion checks each line of the grid to see if it's completely filled.
+func (g *Game) CheckCompletion() {
+	for j := GridVerticalSize - 2; j >= 0; j-- {
+		if g.bits[j] == FullRow && g.rowComplete(j) {
+			g.LineToDelete = true
+
+			// Mark the completed line for deletion
+			for z := 1; z < GridHorizontalSize-1; z++ {
+				g.Grid[z][j] = Fading
+			}
+		}
+	}
+}
+
+// rowComplete reports whether every square of a row inside the walls is Full;
+// blocks, moving and fading squares take room without completing it
+func (g *Game) rowComplete(j int) bool {
+	for i := 1; i < GridHorizontalSize-1; i++ {
+		if g.Grid[i][j] != Full {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteCompleteLines goes through the grid and deletes any lines marked as complete.
+func (g *Game) DeleteCompleteLines() int {
+	deletedLines := 0
+
+	for j := GridVerticalSize - 2; j >= 0; j-- {
+		for g.Grid[1][j] == Fading {
+			// Clear the line
+			for i := 1; i < GridHorizontalSize-1; i++ {
+				g.Grid[i][j] = Empty
+			}
+
+			// Move all lines above down
+			for j2 := j - 1; j2 >= 0; j2-- {
+				for i2 := 1; i2 < GridHorizontalSize-1; i2++ {
+					if g.Grid[i2][j2] == Full || g.Grid[i2][j2] == Fading {
+						g.Grid[i2][j2+1] = g.Grid[i2][j2]
+						g.Grid[i2][j2] = Empty
+					}
+				}
+			}
+
+			// Garbage rows are always the bottom ones
+			if j >= GridVerticalSize-1-g.GarbageLines {
+				g.GarbageLines--
+			}
+
+			deletedLines++
+		}
+	}
+
+	g.bits = NewBitboard(&g.Grid)
+
+	return deletedLines
+}
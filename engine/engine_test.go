@@ -0,0 +1,465 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+// boardGame starts a game on a board written in board notation
+func boardGame(t *testing.T, board string) *Game {
+	t.Helper()
+
+	g := NewGame(DefaultPieceSet(), 1)
+	grid, err := ParseBoard(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetGrid(grid)
+
+	return g
+}
+
+// checkBoard compares the board of a game, active piece included, with a board written in board notation
+func checkBoard(t *testing.T, g *Game, want string) {
+	t.Helper()
+
+	wantGrid, err := ParseBoard(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board := g.Board(); board != wantGrid {
+		t.Errorf("board is\n%s\nwant\n%s", FormatBoard(&board), FormatBoard(&wantGrid))
+	}
+}
+
+func TestCheckCompletion(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+		want  string
+		clear bool
+	}{
+		{
+			name:  "nothing full",
+			board: "#########.\n.#########",
+			want:  "#########.\n.#########",
+		},
+		{
+			name:  "single",
+			board: "..........\n##########",
+			want:  "..........\n~~~~~~~~~~",
+			clear: true,
+		},
+		{
+			name:  "four at once",
+			board: "##########\n##########\n##########\n##########",
+			want:  "~~~~~~~~~~\n~~~~~~~~~~\n~~~~~~~~~~\n~~~~~~~~~~",
+			clear: true,
+		},
+		{
+			name:  "not contiguous",
+			board: "##########\n#####.####\n##########\n####.#####",
+			want:  "~~~~~~~~~~\n#####.####\n~~~~~~~~~~\n####.#####",
+			clear: true,
+		},
+		{
+			name:  "blocks and moving squares",
+			board: "===@@@====\n####==####",
+			want:  "===@@@====\n####==####",
+		},
+		{
+			name:  "top row",
+			board: "##########\n" + strings.Repeat("#########.\n", GridVerticalSize-2),
+			want:  "~~~~~~~~~~\n" + strings.Repeat("#########.\n", GridVerticalSize-2),
+			clear: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := boardGame(t, test.board)
+			g.CheckCompletion()
+
+			checkBoard(t, g, test.want)
+			if g.LineToDelete != test.clear {
+				t.Errorf("LineToDelete is %v, want %v", g.LineToDelete, test.clear)
+			}
+		})
+	}
+}
+
+func TestActivePieceDoesNotCount(t *testing.T) {
+	g := boardGame(t, "####....##")
+	activePiece(t, g, "I", 5, GridVerticalSize-3)
+
+	g.CheckCompletion()
+	checkBoard(t, g, "####@@@@##")
+	if g.LineToDelete {
+		t.Error("the active piece completed a line before locking")
+	}
+}
+
+func TestDeleteCompleteLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+		want  string
+		lines int
+	}{
+		{
+			name:  "nothing fading",
+			board: "#.........\n#########.",
+			want:  "#.........\n#########.",
+		},
+		{
+			name:  "single",
+			board: "#.........\n.#........\n~~~~~~~~~~",
+			want:  "#.........\n.#........",
+			lines: 1,
+		},
+		{
+			name:  "four at once",
+			board: "..#.......\n~~~~~~~~~~\n~~~~~~~~~~\n~~~~~~~~~~\n~~~~~~~~~~",
+			want:  "..#.......",
+			lines: 4,
+		},
+		{
+			name:  "not contiguous",
+			board: "...#......\n~~~~~~~~~~\n#####.####\n~~~~~~~~~~\n####.#####",
+			want:  "...#......\n#####.####\n####.#####",
+			lines: 2,
+		},
+		{
+			name:  "bottom and top of the stack",
+			board: "~~~~~~~~~~\n.#########\n##.#######\n~~~~~~~~~~",
+			want:  ".#########\n##.#######",
+			lines: 2,
+		},
+		{
+			name:  "top row",
+			board: "~~~~~~~~~~\n" + strings.Repeat("#########.\n", GridVerticalSize-2),
+			want:  strings.Repeat("#########.\n", GridVerticalSize-2),
+			lines: 1,
+		},
+		{
+			name:  "whole grid",
+			board: strings.Repeat("~~~~~~~~~~\n", GridVerticalSize-1),
+			want:  "",
+			lines: GridVerticalSize - 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := boardGame(t, test.board)
+
+			if lines := g.DeleteCompleteLines(); lines != test.lines {
+				t.Errorf("deleted %d lines, want %d", lines, test.lines)
+			}
+			checkBoard(t, g, test.want)
+		})
+	}
+}
+
+func TestDeleteGarbageLines(t *testing.T) {
+	g := boardGame(t, "#.........")
+	g.AddGarbage(3, 4)
+	checkBoard(t, g, "#.........\n###.######\n###.######\n###.######")
+
+	g.CheckCompletion()
+	if g.LineToDelete {
+		t.Fatal("garbage rows with a hole are complete")
+	}
+
+	// Filling the hole of the middle row clears it
+	grid := g.Grid
+	grid[4][GridVerticalSize-3] = Full
+	g.SetGrid(grid)
+	g.CheckCompletion()
+	if lines := g.DeleteCompleteLines(); lines != 1 {
+		t.Fatalf("deleted %d lines, want 1", lines)
+	}
+	checkBoard(t, g, "#.........\n###.######\n###.######")
+	if g.GarbageLines != 2 || g.GarbageAdded != 3 {
+		t.Errorf("garbage lines %d added %d, want 2 and 3", g.GarbageLines, g.GarbageAdded)
+	}
+}
+
+// activePiece makes a piece of the default set in its first rotation the active piece of a game
+func activePiece(t *testing.T, g *Game, name string, x, y int) {
+	t.Helper()
+
+	g.PieceType = g.Set.Index(name)
+	g.PieceRotation = 0
+	if !g.CanPlace(g.PieceType, 0, x, y) {
+		t.Fatalf("no room for %s at %d,%d", name, x, y)
+	}
+
+	g.PiecePositionX = x
+	g.PiecePositionY = y
+	g.PieceActive = true
+}
+
+func TestCanPlace(t *testing.T) {
+	g := boardGame(t, "#.........\n##.......#")
+	i := g.Set.Index("I")
+
+	tests := []struct {
+		name                  string
+		piece, rotation, x, y int
+		want                  bool
+	}{
+		{"open grid", i, 0, 4, 5, true},
+		{"left wall", i, 0, 0, 5, false},
+		{"against the left wall", i, 0, 1, 5, true},
+		{"right wall", i, 0, GridHorizontalSize - 4, 5, false},
+		{"past the right wall", i, 1, GridHorizontalSize, 5, false},
+		{"above the grid", i, 1, 4, -1, false},
+		{"box above the grid", i, 0, 4, -1, true},
+		{"floor", i, 0, 4, GridVerticalSize - 2, false},
+		{"full square", i, 0, 1, GridVerticalSize - 4, false},
+		{"over a full square", i, 0, 3, GridVerticalSize - 4, true},
+		{"unknown rotation", i, 4, 4, 5, false},
+		{"unknown piece", len(g.Set.Pieces), 0, 4, 5, false},
+	}
+
+	for _, test := range tests {
+		if got := g.CanPlace(test.piece, test.rotation, test.x, test.y); got != test.want {
+			t.Errorf("%s: CanPlace is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPlace(t *testing.T) {
+	g := boardGame(t, "")
+	activePiece(t, g, "I", 4, 5)
+
+	if !g.Place(1, 4, 5) {
+		t.Fatal("cannot turn in the open")
+	}
+	checkBoard(t, g, "....@.....\n....@.....\n....@.....\n....@.....\n"+strings.Repeat("..........\n", GridVerticalSize-1-9))
+
+	// Turning back against the wall does not fit, and leaves the piece alone
+	if !g.Place(1, GridHorizontalSize-3, 5) {
+		t.Fatal("cannot move against the right wall")
+	}
+	before := g.Grid
+	if g.Place(0, GridHorizontalSize-3, 5) {
+		t.Fatal("turned into the right wall")
+	}
+	if g.Grid != before || g.PieceRotation != 1 || g.PiecePositionX != GridHorizontalSize-3 {
+		t.Error("a move that does not fit changed the game")
+	}
+}
+
+func TestBoard(t *testing.T) {
+	g := boardGame(t, "#.........")
+	activePiece(t, g, "O", 4, GridVerticalSize-4)
+
+	// The active piece is drawn in, but stays out of the grid until it locks
+	checkBoard(t, g, "....@@....\n#...@@....")
+	if want, _ := ParseBoard("#........."); g.Grid != want {
+		t.Errorf("the grid holds the active piece\n%s", FormatBoard(&g.Grid))
+	}
+
+	g.PieceActive = false
+	checkBoard(t, g, "#.........")
+}
+
+func TestLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+		piece string
+		x, y  int // Top left corner of the box of the piece
+		want  string
+		lock  bool
+	}{
+		{
+			name:  "falling",
+			piece: "I", x: 5, y: GridVerticalSize - 4,
+			want: "....@@@@..",
+		},
+		{
+			name:  "on the floor",
+			piece: "I", x: 5, y: GridVerticalSize - 3,
+			want: "....####..",
+			lock: true,
+		},
+		{
+			name:  "on a full square",
+			board: ".......#..",
+			piece: "I", x: 5, y: GridVerticalSize - 4,
+			want: "....####..\n.......#..",
+			lock: true,
+		},
+		{
+			name:  "beside full squares",
+			board: "...#....#.\n...#....#.\n##########",
+			piece: "I", x: 5, y: GridVerticalSize - 5,
+			want: "...#....#.\n...#@@@@#.\n##########",
+		},
+		{
+			name:  "hanging over a hole",
+			board: "..#.......",
+			piece: "Z", x: 1, y: GridVerticalSize - 5,
+			want: ".##.......\n..##......\n..#.......",
+			lock: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := boardGame(t, test.board)
+			activePiece(t, g, test.piece, test.x, test.y)
+
+			g.CheckDetection()
+			g.ResolveFallingMovement()
+
+			checkBoard(t, g, test.want)
+			if g.PieceActive == test.lock {
+				t.Errorf("PieceActive is %v after the fall, want %v", g.PieceActive, !test.lock)
+			}
+			if locked := g.Pieces == 1; locked != test.lock {
+				t.Errorf("%d pieces locked, want the lock to be %v", g.Pieces, test.lock)
+			}
+		})
+	}
+}
+
+func TestStepClearsLines(t *testing.T) {
+	// The I piece spawns flat over the gap and clears the line when dropped
+	g := boardGame(t, "###....###")
+	g.IncomingType = g.Set.Index("I")
+
+	for i := 0; i < 1000 && g.Lines == 0; i++ {
+		g.Step(InputDown)
+	}
+
+	if g.Lines != 1 || g.Score != LineScores[1] || g.Clears[1] != 1 {
+		t.Fatalf("lines %d score %d clears %v, want a single", g.Lines, g.Score, g.Clears)
+	}
+	if g.Pieces != 1 {
+		t.Errorf("%d pieces locked, want 1", g.Pieces)
+	}
+	if g.GameOver {
+		t.Error("game over after clearing the only line")
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		gameOver bool
+	}{
+		{
+			name:  "empty grid",
+			board: "",
+		},
+		{
+			name:  "stack below the spawn rows",
+			board: strings.Repeat("#.........\n", GridVerticalSize-3),
+		},
+		{
+			name:     "full square in the second row",
+			board:    strings.Repeat("#.........\n", GridVerticalSize-2),
+			gameOver: true,
+		},
+		{
+			name:     "full square in the top row",
+			board:    "........#.\n" + strings.Repeat("..........\n", GridVerticalSize-2),
+			gameOver: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := boardGame(t, test.board)
+			g.Step(0)
+
+			if g.GameOver != test.gameOver {
+				t.Errorf("GameOver is %v, want %v", g.GameOver, test.gameOver)
+			}
+		})
+	}
+}
+
+func TestGameOverStopsTheGame(t *testing.T) {
+	g := boardGame(t, strings.Repeat("#.........\n", GridVerticalSize-2))
+	g.Step(0)
+	if !g.GameOver {
+		t.Fatal("no game over")
+	}
+
+	before := g.Grid
+	g.Step(InputDown)
+	if g.Grid != before || g.Frame != 1 {
+		t.Error("the game went on after game over")
+	}
+}
+
+func TestGarbageTopOut(t *testing.T) {
+	g := boardGame(t, "#.........\n"+strings.Repeat("#########.\n", GridVerticalSize-4))
+	g.AddGarbage(2, 1)
+	if g.GameOver {
+		t.Fatal("game over with room left at the top")
+	}
+
+	g.AddGarbage(1, 1)
+	if !g.GameOver {
+		t.Fatal("no game over when the stack is pushed out of the grid")
+	}
+}
+
+func FuzzDeleteCompleteLines(f *testing.F) {
+	f.Add([]byte{0xff, 0xff, 0x03, 0x00, 0xff, 0xff})
+	f.Add([]byte{0xff, 0x03, 0xff, 0x03, 0xfe, 0x03})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Every two bytes fill the ten squares of a row from the bottom up
+		g := NewGame(DefaultPieceSet(), 1)
+		grid := NewGrid()
+		var kept []string
+		full := 0
+
+		for n := 0; n+1 < len(data) && n/2 < GridVerticalSize-1; n += 2 {
+			bits := int(data[n]) | int(data[n+1])<<8
+			j := GridVerticalSize - 2 - n/2
+
+			row := make([]byte, GridHorizontalSize-2)
+			for i := range row {
+				row[i] = EmptyChar
+				if bits&(1<<i) != 0 {
+					row[i] = FullChar
+					grid[i+1][j] = Full
+				}
+			}
+
+			if strings.Contains(string(row), string(EmptyChar)) {
+				kept = append([]string{string(row)}, kept...)
+			} else {
+				full++
+			}
+		}
+
+		g.SetGrid(grid)
+		g.CheckCompletion()
+		if g.LineToDelete != (full > 0) {
+			t.Fatalf("LineToDelete is %v with %d full rows", g.LineToDelete, full)
+		}
+
+		if lines := g.DeleteCompleteLines(); lines != full {
+			t.Fatalf("deleted %d lines, want %d", lines, full)
+		}
+
+		// The other rows keep their order and fall onto each other
+		want, err := ParseRows(kept)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Grid != want {
+			t.Fatalf("board is\n%s\nwant\n%s", FormatBoard(&g.Grid), FormatBoard(&want))
+		}
+	})
+}
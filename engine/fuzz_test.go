@@ -0,0 +1,142 @@
+package engine
+
+import "testing"
+
+// checkInvariants fails the test when a game breaks a rule that must hold after every step
+func checkInvariants(t *testing.T, g *Game, lines int) {
+	t.Helper()
+
+	walls := NewGrid()
+	for i := 0; i < GridHorizontalSize; i++ {
+		for j := 0; j < GridVerticalSize; j++ {
+			square := g.Grid[i][j]
+			if (walls[i][j] == Block) != (square == Block) {
+				t.Fatalf("square %d,%d is %d, the walls and floor changed\n%s", i, j, square, FormatBoard(&g.Grid))
+			}
+			if square == Moving {
+				t.Fatalf("moving square %d,%d in the grid\n%s", i, j, FormatBoard(&g.Grid))
+			}
+		}
+	}
+
+	if g.bits != NewBitboard(&g.Grid) {
+		t.Fatalf("the bitboard is out of step with the grid\n%s", FormatBoard(&g.Grid))
+	}
+
+	if g.Lines < lines {
+		t.Fatalf("lines went down from %d to %d", lines, g.Lines)
+	}
+
+	// The active piece only covers empty squares inside the grid
+	if g.PieceActive && !g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY) {
+		board := g.Board()
+		t.Fatalf("%s in rotation %d at %d,%d does not fit\n%s", g.Set.Pieces[g.PieceType].Name, g.PieceRotation, g.PiecePositionX, g.PiecePositionY, FormatBoard(&board))
+	}
+}
+
+// play steps a game through a stream of actions, checking the invariants
+// after every step. The low bits of an action are the buttons held, and
+// the top bit sends a garbage row with its hole in the next three bits.
+func play(t *testing.T, g *Game, actions []byte) {
+	for _, action := range actions {
+		if action&0x80 != 0 {
+			g.ReceiveGarbage(1, 1+int(action>>4&0x7)%(GridHorizontalSize-2), 0)
+		}
+
+		lines := g.Lines
+		g.Step(Input(action & 0x1f))
+		if g.GameOver {
+			return
+		}
+		checkInvariants(t, g, lines)
+	}
+}
+
+func FuzzStep(f *testing.F) {
+	f.Add(int64(1), []byte{0, 0, 8, 8, 8, 8})
+	f.Add(int64(2), []byte{2, 0, 2, 0, 2, 0, 2, 0, 2, 0, 4, 0, 4, 0, 4, 0, 8, 8, 8})
+	f.Add(int64(3), []byte{1, 4, 1, 4, 16, 0, 16, 0x88, 0x98, 0xa8, 4, 4})
+
+	f.Fuzz(func(t *testing.T, seed int64, actions []byte) {
+		// Short streams still reach the floor by repeating
+		for len(actions) > 0 && len(actions) < 1000 {
+			actions = append(actions, actions...)
+		}
+
+		play(t, NewGame(DefaultPieceSet(), seed), actions)
+	})
+}
+
+func FuzzStepPieceSets(f *testing.F) {
+	f.Add(int64(1), uint8(0), []byte{2, 0, 2, 0, 2, 0, 4, 0, 4, 0, 8})
+	f.Add(int64(2), uint8(1), []byte{1, 0, 1, 0, 1, 0, 4, 0, 4, 0, 8})
+	f.Add(int64(3), uint8(2), []byte{4, 0, 2, 0, 2, 0, 2, 0, 2, 0, 4, 0})
+
+	sets := []string{"../piecesets/pentominoes.json", "../piecesets/trominoes.json", "../piecesets/bigblock.json"}
+
+	f.Fuzz(func(t *testing.T, seed int64, set uint8, actions []byte) {
+		pieces, err := LoadPieceSet(sets[int(set)%len(sets)])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for len(actions) > 0 && len(actions) < 1000 {
+			actions = append(actions, actions...)
+		}
+
+		play(t, NewGame(pieces, seed), actions)
+	})
+}
+
+func FuzzStepTimings(f *testing.F) {
+	f.Add(int64(1), uint8(1), []byte{2, 2, 2, 2, 0, 4, 0, 8, 8, 8})
+	f.Add(int64(2), uint8(2), []byte{1, 1, 1, 1, 1, 1, 0, 8, 0, 16, 8})
+	f.Add(int64(3), uint8(4), []byte{0x82, 0x82, 4, 0, 1, 1, 1, 8, 8})
+
+	f.Fuzz(func(t *testing.T, seed int64, timing uint8, actions []byte) {
+		for len(actions) > 0 && len(actions) < 1000 {
+			actions = append(actions, actions...)
+		}
+
+		g := NewGame(DefaultPieceSet(), seed)
+		g.Timing = Timings[int(timing)%len(Timings)]
+		play(t, g, actions)
+	})
+}
+
+func TestTurnAtWalls(t *testing.T) {
+	sets := []string{"../piecesets/pentominoes.json", "../piecesets/trominoes.json", "../piecesets/bigblock.json"}
+
+	for _, path := range append([]string{""}, sets...) {
+		pieces := DefaultPieceSet()
+		if path != "" {
+			var err error
+			if pieces, err = LoadPieceSet(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for pieceType, piece := range pieces.Pieces {
+			for _, side := range []Input{InputLeft, InputRight} {
+				t.Run(pieces.Name+"/"+piece.Name, func(t *testing.T) {
+					g := NewGame(pieces, 1)
+					g.IncomingType = pieceType
+
+					// Push against the wall, then keep turning all the way down
+					var actions []byte
+					for i := 0; i < GridHorizontalSize; i++ {
+						actions = append(actions, byte(side), 0)
+					}
+					for i := 0; i < 200; i++ {
+						actions = append(actions, byte(InputRotate), 0, byte(side|InputDown), byte(InputDown))
+					}
+
+					play(t, g, actions)
+					if g.Pieces == 0 {
+						t.Error("the piece never locked")
+					}
+				})
+			}
+		}
+	}
+}
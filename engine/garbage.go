@@ -0,0 +1,119 @@
+package engine
+
+// MaxPendingGarbage is how many attacks can wait to enter the grid at once
+const MaxPendingGarbage = 16
+
+// GarbageAttack is the number of garbage rows sent by clearing 0 to MaxPieceSize lines at once
+var GarbageAttack = [MaxPieceSize + 1]int{0, 0, 1, 2, 4, 5}
+
+// Garbage is an attack waiting to enter the grid
+type Garbage struct {
+	Rows  int
+	Hole  int // Column left empty in every row
+	Delay int // Frames left before it can enter
+}
+
+// ReceiveGarbage queues garbage rows sent by an opponent. They enter the
+// grid when the next piece spawns once the delay has run out, unless lines
+// cleared in the meantime cancel them.
+func (g *Game) ReceiveGarbage(rows, hole, delay int) {
+	if rows <= 0 {
+		return
+	}
+
+	if g.PendingGarbage == MaxPendingGarbage {
+		g.Garbage[MaxPendingGarbage-1].Rows += rows
+		return
+	}
+
+	g.Garbage[g.PendingGarbage] = Garbage{Rows: rows, Hole: hole, Delay: delay}
+	g.PendingGarbage++
+}
+
+// PendingGarbageRows returns the number of garbage rows waiting to enter the grid
+func (g *Game) PendingGarbageRows() int {
+	rows := 0
+	for i := 0; i < g.PendingGarbage; i++ {
+		rows += g.Garbage[i].Rows
+	}
+	return rows
+}
+
+// AddGarbage pushes everything in the grid up and fills the bottom rows with
+// garbage, leaving the hole column empty. The active piece goes up with the
+// stack. Squares pushed out of the top of the grid end the game.
+func (g *Game) AddGarbage(rows, hole int) {
+	rows = min(rows, GridVerticalSize-1)
+
+	for j := 0; j < rows; j++ {
+		if g.bits[j]&InteriorRow != 0 {
+			g.GameOver = true
+		}
+	}
+
+	for j := 0; j < GridVerticalSize-1-rows; j++ {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			g.Grid[i][j] = g.Grid[i][j+rows]
+		}
+	}
+
+	for j := GridVerticalSize - 1 - rows; j < GridVerticalSize-1; j++ {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			if i == hole {
+				g.Grid[i][j] = Empty
+			} else {
+				g.Grid[i][j] = Full
+			}
+		}
+	}
+
+	g.bits = NewBitboard(&g.Grid)
+
+	g.PiecePositionY -= rows
+	if g.PieceActive && !g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY) {
+		g.GameOver = true
+	}
+	g.GarbageLines = min(g.GarbageLines+rows, GridVerticalSize-1)
+	g.GarbageAdded += rows
+}
+
+// tickGarbage counts down the delay of the pending garbage
+func (g *Game) tickGarbage() {
+	for i := 0; i < g.PendingGarbage; i++ {
+		if g.Garbage[i].Delay > 0 {
+			g.Garbage[i].Delay--
+		}
+	}
+}
+
+// enterGarbage adds the pending garbage whose delay has run out to the grid
+func (g *Game) enterGarbage() {
+	for g.PendingGarbage > 0 && g.Garbage[0].Delay == 0 {
+		g.AddGarbage(g.Garbage[0].Rows, g.Garbage[0].Hole)
+		g.dropGarbage()
+	}
+}
+
+// sendGarbage cancels pending garbage with the attack of a clear and counts what is left as sent
+func (g *Game) sendGarbage(lines int) {
+	attack := GarbageAttack[min(lines, MaxPieceSize)]
+
+	for attack > 0 && g.PendingGarbage > 0 {
+		cancel := min(attack, g.Garbage[0].Rows)
+		g.Garbage[0].Rows -= cancel
+		attack -= cancel
+
+		if g.Garbage[0].Rows == 0 {
+			g.dropGarbage()
+		}
+	}
+
+	g.LinesSent += attack
+}
+
+// dropGarbage removes the oldest pending attack
+func (g *Game) dropGarbage() {
+	copy(g.Garbage[:], g.Garbage[1:g.PendingGarbage])
+	g.PendingGarbage--
+	g.Garbage[g.PendingGarbage] = Garbage{}
+}
@@ -0,0 +1,132 @@
+package engine
+
+import "testing"
+
+func TestGarbageAttack(t *testing.T) {
+	for lines, want := range GarbageAttack {
+		g := NewGame(DefaultPieceSet(), 1)
+		g.sendGarbage(lines)
+
+		if g.LinesSent != want {
+			t.Errorf("clearing %d lines sent %d rows, want %d", lines, g.LinesSent, want)
+		}
+	}
+}
+
+func TestClearSendsGarbage(t *testing.T) {
+	// The O piece spawns over the gap and clears both lines when dropped
+	g := boardGame(t, "####..####\n####..####")
+	g.IncomingType = g.Set.Index("O")
+
+	stepUntil(t, g, InputDown, func() bool { return g.Lines > 0 })
+	if g.Lines != 2 || g.LinesSent != GarbageAttack[2] {
+		t.Errorf("cleared %d lines and sent %d rows, want 2 and %d", g.Lines, g.LinesSent, GarbageAttack[2])
+	}
+}
+
+func TestGarbageCancelling(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming []int // Rows of every attack received
+		lines    int   // Lines cleared at once
+		pending  []int // Rows of every attack left
+		sent     int
+	}{
+		{name: "nothing incoming", lines: 4, sent: 4},
+		{name: "all cancelled", incoming: []int{4}, lines: 4},
+		{name: "more sent than received", incoming: []int{1}, lines: 3, sent: 1},
+		{name: "partly cancelled", incoming: []int{3}, lines: 3, pending: []int{1}},
+		{name: "oldest first", incoming: []int{1, 2, 2}, lines: 4, pending: []int{1}},
+		{name: "single cancels nothing", incoming: []int{2}, lines: 1, pending: []int{2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGame(DefaultPieceSet(), 1)
+			for _, rows := range test.incoming {
+				g.ReceiveGarbage(rows, 1, 60)
+			}
+
+			g.sendGarbage(test.lines)
+
+			if g.LinesSent != test.sent {
+				t.Errorf("sent %d rows, want %d", g.LinesSent, test.sent)
+			}
+			if g.PendingGarbage != len(test.pending) {
+				t.Fatalf("%d attacks pending, want %d", g.PendingGarbage, len(test.pending))
+			}
+			for i, rows := range test.pending {
+				if g.Garbage[i].Rows != rows {
+					t.Errorf("attack %d has %d rows left, want %d", i, g.Garbage[i].Rows, rows)
+				}
+			}
+		})
+	}
+}
+
+func TestReceiveGarbage(t *testing.T) {
+	g := NewGame(DefaultPieceSet(), 1)
+
+	g.ReceiveGarbage(0, 1, 10)
+	if g.PendingGarbage != 0 {
+		t.Fatal("an empty attack is pending")
+	}
+
+	// Attacks past the limit join the last one
+	for i := 0; i < MaxPendingGarbage+2; i++ {
+		g.ReceiveGarbage(1, 1, 10)
+	}
+	if g.PendingGarbage != MaxPendingGarbage || g.Garbage[MaxPendingGarbage-1].Rows != 3 {
+		t.Errorf("%d attacks pending, the last of %d rows", g.PendingGarbage, g.Garbage[MaxPendingGarbage-1].Rows)
+	}
+	if g.PendingGarbageRows() != MaxPendingGarbage+2 {
+		t.Errorf("%d rows pending, want %d", g.PendingGarbageRows(), MaxPendingGarbage+2)
+	}
+}
+
+func TestGarbageDelay(t *testing.T) {
+	const delay, hole = 40, 5
+
+	g := NewGame(DefaultPieceSet(), 1)
+	g.ReceiveGarbage(2, hole, delay)
+
+	// Pieces dropped before the delay runs out leave the garbage waiting
+	entered := stepUntil(t, g, InputDown, func() bool { return g.PendingGarbage == 0 })
+	if entered < delay {
+		t.Errorf("garbage entered on frame %d, before its delay of %d", entered, delay)
+	}
+	if g.Spawned < 2 {
+		t.Errorf("garbage entered before the next piece spawned")
+	}
+	if g.GarbageLines != 2 || g.GarbageAdded != 2 {
+		t.Errorf("%d garbage lines in the grid and %d added, want 2", g.GarbageLines, g.GarbageAdded)
+	}
+
+	// Both bottom rows are full but for the hole column
+	for j := GridVerticalSize - 3; j < GridVerticalSize-1; j++ {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			want := Full
+			if i == hole {
+				want = Empty
+			}
+			if g.Grid[i][j] != want {
+				board := g.Board()
+				t.Fatalf("square %d,%d is %v, want %v\n%s", i, j, g.Grid[i][j], want, FormatBoard(&board))
+			}
+		}
+	}
+}
+
+func TestAddGarbagePushesThePieceUp(t *testing.T) {
+	g := NewGame(DefaultPieceSet(), 1)
+	stepUntil(t, g, 0, func() bool { return g.PieceActive && g.PiecePositionY >= 5 })
+	y := g.PiecePositionY
+
+	g.AddGarbage(3, 2)
+	if g.PiecePositionY != y-3 {
+		t.Errorf("piece at row %d, want %d", g.PiecePositionY, y-3)
+	}
+	if g.bits != NewBitboard(&g.Grid) {
+		t.Error("bitboard out of step with the grid")
+	}
+}
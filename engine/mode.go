@@ -0,0 +1,189 @@
+package engine
+
+import "fmt"
+
+// Mode is a set of rules on top of the engine: how the game starts and when
+// it is finished. Modes only hold their settings; everything that changes
+// during a game lives in the Game so copies of it stay independent.
+type Mode interface {
+	// Name is shown to the player
+	Name() string
+
+	// Setup prepares a new game before its first piece
+	Setup(g *Game)
+
+	// Update runs after every step, setting Finished once the goal is reached
+	Update(g *Game)
+}
+
+// LinesPerLevel is the number of lines cleared between two levels of a marathon
+const LinesPerLevel = 10
+
+// GravityCurve is the number of frames a piece takes to fall one row at
+// each level from 1, the last entry holding for every level above
+var GravityCurve = []int{30, 26, 22, 19, 16, 13, 11, 9, 7, 6, 5, 4, 3, 3, 2, 2, 2, 1}
+
+// GravityFrames returns the number of frames a piece takes to fall one row at a level
+func GravityFrames(level int) int {
+	return GravityCurve[min(max(level, 1), len(GravityCurve))-1]
+}
+
+// Marathon goes up a level, falling faster, every LinesPerLevel lines, and
+// is finished once Lines lines have been cleared. With no line goal it goes
+// on until the stack tops out.
+type Marathon struct {
+	StartLevel int
+	Lines      int // 0 for endless play
+}
+
+// Name of the mode, with its goal
+func (m Marathon) Name() string {
+	if m.Lines <= 0 {
+		return "MARATHON ENDLESS"
+	}
+	return fmt.Sprintf("MARATHON %d", m.Lines)
+}
+
+// Setup starts at the chosen level
+func (m Marathon) Setup(g *Game) {
+	g.Level = max(m.StartLevel, 1)
+	g.gravitySpeed = GravityFrames(g.Level)
+}
+
+// Update levels up as lines are cleared and finishes the game at the goal
+func (m Marathon) Update(g *Game) {
+	if level := max(m.StartLevel, 1) + g.Lines/LinesPerLevel; level != g.Level {
+		g.Level = level
+		g.gravitySpeed = GravityFrames(level)
+	}
+
+	if m.Lines > 0 && g.Lines >= m.Lines {
+		g.Finished = true
+	}
+}
+
+// MaxSplits is how many split times a game keeps
+const MaxSplits = 32
+
+// SprintSplitLines is the number of lines between two split times of a sprint
+const SprintSplitLines = 10
+
+// Sprint is finished once Lines lines have been cleared, recording a split
+// time every SprintSplitLines lines on the way.
+type Sprint struct {
+	Lines int
+}
+
+// Name of the mode, with its goal
+func (s Sprint) Name() string {
+	return fmt.Sprintf("SPRINT %d", s.Lines)
+}
+
+// Setup has nothing to prepare, the grid starts empty
+func (s Sprint) Setup(g *Game) {}
+
+// Update records the split times and finishes the game at the goal
+func (s Sprint) Update(g *Game) {
+	for g.SplitCount < MaxSplits && g.Lines >= (g.SplitCount+1)*SprintSplitLines && (g.SplitCount+1)*SprintSplitLines <= s.Lines {
+		g.Splits[g.SplitCount] = g.Frame
+		g.SplitCount++
+	}
+
+	if g.Lines >= s.Lines {
+		g.Finished = true
+	}
+}
+
+// CountdownFrames is the length of the countdown before a timed game starts
+const CountdownFrames = 180
+
+// Ultra is a score attack finished once Frames frames have been played,
+// after a countdown.
+type Ultra struct {
+	Frames int
+}
+
+// Name of the mode, with its time limit
+func (u Ultra) Name() string {
+	seconds := u.Frames / FrameRate
+	return fmt.Sprintf("ULTRA %d:%02d", seconds/60, seconds%60)
+}
+
+// Setup starts the countdown
+func (u Ultra) Setup(g *Game) {
+	g.Countdown = CountdownFrames
+}
+
+// Update finishes the game when the time is up
+func (u Ultra) Update(g *Game) {
+	if g.Frame >= u.Frames {
+		g.Finished = true
+	}
+}
+
+// CheeseRace fills the bottom of the grid with messy garbage, each row with
+// its own hole, and is finished once every garbage row has been cleared.
+// Only Visible rows are in the grid at once, more come in as they are dug out.
+type CheeseRace struct {
+	Lines   int
+	Visible int
+}
+
+// Name of the mode
+func (c CheeseRace) Name() string {
+	return "CHEESE RACE"
+}
+
+// Setup adds the first garbage rows
+func (c CheeseRace) Setup(g *Game) {
+	c.refill(g)
+}
+
+// Update adds garbage rows between pieces and finishes the game once they are all gone
+func (c CheeseRace) Update(g *Game) {
+	if g.PieceActive || g.LineToDelete {
+		return
+	}
+
+	c.refill(g)
+
+	if g.GarbageLines == 0 && g.GarbageAdded >= c.Lines {
+		g.Finished = true
+	}
+}
+
+// Remaining returns the garbage rows left to clear
+func (c CheeseRace) Remaining(g *Game) int {
+	return c.Lines - g.GarbageAdded + g.GarbageLines
+}
+
+// refill tops the garbage up to the visible rows while some are left to add
+func (c CheeseRace) refill(g *Game) {
+	visible := c.Visible
+	if visible <= 0 {
+		visible = c.Lines
+	}
+
+	for g.GarbageLines < visible && g.GarbageAdded < c.Lines && !g.GameOver {
+		g.AddGarbage(1, g.garbageHole())
+	}
+}
+
+// garbageHole picks a hole column for a new bottom garbage row, never right under the hole of the previous one
+func (g *Game) garbageHole() int {
+	previous := -1
+	if g.GarbageLines > 0 {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			if g.Grid[i][GridVerticalSize-2] == Empty {
+				previous = i
+			}
+		}
+	}
+
+	hole := 1 + g.random.Intn(GridHorizontalSize-3)
+	if previous > 0 && hole >= previous {
+		hole++
+	}
+
+	return hole
+}
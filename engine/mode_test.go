@@ -0,0 +1,210 @@
+package engine
+
+import "testing"
+
+// holes returns the hole column of each of the bottom rows of the grid, from the bottom up
+func holes(t *testing.T, g *Game, rows int) []int {
+	t.Helper()
+
+	var found []int
+	for j := GridVerticalSize - 2; j > GridVerticalSize-2-rows; j-- {
+		hole := -1
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			if g.Grid[i][j] != Empty {
+				continue
+			}
+			if hole > 0 {
+				board := g.Board()
+				t.Fatalf("row %d has more than one hole\n%s", j, FormatBoard(&board))
+			}
+			hole = i
+		}
+		found = append(found, hole)
+	}
+
+	return found
+}
+
+// digGarbage fills the holes of the top garbage rows and clears them
+func digGarbage(g *Game, rows int) {
+	for j := GridVerticalSize - 1 - g.GarbageLines; j < GridVerticalSize-1-g.GarbageLines+rows; j++ {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			g.Grid[i][j] = Full
+		}
+	}
+	g.SetGrid(g.Grid)
+	g.CheckCompletion()
+	g.DeleteCompleteLines()
+	g.LineToDelete = false
+}
+
+// checkHoles fails unless every row has a hole and no two holes are above each other
+func checkHoles(t *testing.T, found []int) {
+	t.Helper()
+
+	for i := range found {
+		if found[i] < 1 || i > 0 && found[i] == found[i-1] {
+			t.Errorf("holes %v, want one in every row and never two above each other", found)
+			return
+		}
+	}
+}
+
+func TestCheeseRace(t *testing.T) {
+	mode := CheeseRace{Lines: 7, Visible: 4}
+	g := NewModeGame(DefaultPieceSet(), 1, mode)
+
+	if g.GarbageLines != 4 || g.GarbageAdded != 4 || mode.Remaining(g) != 7 {
+		t.Fatalf("%d garbage lines in the grid and %d added, %d remaining, want 4, 4 and 7", g.GarbageLines, g.GarbageAdded, mode.Remaining(g))
+	}
+	checkHoles(t, holes(t, g, 4))
+
+	// Digging out two rows lets two more in once the piece is gone
+	digGarbage(g, 2)
+	if g.GarbageLines != 2 || mode.Remaining(g) != 5 {
+		t.Fatalf("%d garbage lines left in the grid, %d remaining, want 2 and 5", g.GarbageLines, mode.Remaining(g))
+	}
+	g.PieceActive = false
+	mode.Update(g)
+	if g.GarbageLines != 4 || g.GarbageAdded != 6 || mode.Remaining(g) != 5 || g.Finished {
+		t.Fatalf("%d garbage lines in the grid and %d added, %d remaining, want 4, 6 and 5", g.GarbageLines, g.GarbageAdded, mode.Remaining(g))
+	}
+	checkHoles(t, holes(t, g, 4))
+
+	// Rows are never added under a piece in play
+	digGarbage(g, 4)
+	g.PieceActive = true
+	mode.Update(g)
+	if g.GarbageLines != 0 || g.Finished {
+		t.Fatalf("%d garbage lines added while a piece was in play", g.GarbageLines)
+	}
+
+	// The last row comes in, and the race is only over once it is cleared too
+	g.PieceActive = false
+	mode.Update(g)
+	if g.GarbageLines != 1 || g.GarbageAdded != 7 || mode.Remaining(g) != 1 || g.Finished {
+		t.Fatalf("%d garbage lines in the grid and %d added, %d remaining, want 1, 7 and 1", g.GarbageLines, g.GarbageAdded, mode.Remaining(g))
+	}
+
+	digGarbage(g, 1)
+	mode.Update(g)
+	if !g.Finished || mode.Remaining(g) != 0 || g.GarbageAdded != 7 {
+		t.Errorf("finished %v with %d remaining and %d added, want the race over", g.Finished, mode.Remaining(g), g.GarbageAdded)
+	}
+}
+
+func TestCheeseRaceAllVisible(t *testing.T) {
+	mode := CheeseRace{Lines: 15}
+
+	for seed := int64(1); seed <= 20; seed++ {
+		g := NewModeGame(DefaultPieceSet(), seed, mode)
+
+		if g.GarbageLines != 15 || mode.Remaining(g) != 15 {
+			t.Fatalf("seed %d: %d garbage lines in the grid, %d remaining, want every row in at once", seed, g.GarbageLines, mode.Remaining(g))
+		}
+		checkHoles(t, holes(t, g, 15))
+	}
+}
+
+func TestUltra(t *testing.T) {
+	mode := Ultra{Frames: 5 * FrameRate}
+	if name := mode.Name(); name != "ULTRA 0:05" {
+		t.Errorf("named %q, want ULTRA 0:05", name)
+	}
+	if name := (Ultra{Frames: 3 * 60 * FrameRate}).Name(); name != "ULTRA 3:00" {
+		t.Errorf("named %q, want ULTRA 3:00", name)
+	}
+
+	g := NewModeGame(DefaultPieceSet(), 1, mode)
+
+	// Nothing moves and the clock does not run during the countdown
+	for i := 0; i < CountdownFrames; i++ {
+		g.Step(InputDown)
+	}
+	if g.Countdown != 0 || g.Frame != 0 || g.PieceActive {
+		t.Fatalf("after the countdown: %d frames left, clock at %d, piece active %v", g.Countdown, g.Frame, g.PieceActive)
+	}
+
+	// The game is finished the moment the time is up, and stays so
+	if frame := stepUntil(t, g, InputDown, func() bool { return g.Finished }); frame != mode.Frames {
+		t.Errorf("finished on frame %d, want %d", frame, mode.Frames)
+	}
+	if g.GameOver {
+		t.Error("the time limit topped the game out")
+	}
+	g.Step(InputDown)
+	if g.Frame != mode.Frames {
+		t.Errorf("clock ran on to %d after the time was up", g.Frame)
+	}
+}
+
+func TestMarathonLevels(t *testing.T) {
+	tests := []struct {
+		start, lines int
+		level        int
+	}{
+		{start: 0, lines: 0, level: 1},
+		{start: 1, lines: 9, level: 1},
+		{start: 1, lines: 10, level: 2},
+		{start: 1, lines: 35, level: 4},
+		{start: 5, lines: 0, level: 5},
+		{start: 5, lines: 19, level: 6},
+		{start: 15, lines: 100, level: 25},
+	}
+
+	for _, test := range tests {
+		mode := Marathon{StartLevel: test.start}
+		g := NewModeGame(DefaultPieceSet(), 1, mode)
+		g.Lines = test.lines
+		mode.Update(g)
+
+		if g.Level != test.level || g.gravitySpeed != GravityFrames(test.level) {
+			t.Errorf("level %d after %d lines: at level %d falling a row every %d frames, want level %d and %d frames",
+				test.start, test.lines, g.Level, g.gravitySpeed, test.level, GravityFrames(test.level))
+		}
+		if g.Finished {
+			t.Errorf("level %d after %d lines: an endless marathon finished", test.start, test.lines)
+		}
+	}
+}
+
+func TestGravityFrames(t *testing.T) {
+	if GravityFrames(1) != GravitySpeedInitial {
+		t.Errorf("level 1 falls a row every %d frames, want %d", GravityFrames(1), GravitySpeedInitial)
+	}
+	for level := 2; level <= len(GravityCurve); level++ {
+		if GravityFrames(level) > GravityFrames(level-1) {
+			t.Errorf("level %d falls slower than level %d", level, level-1)
+		}
+	}
+	if GravityFrames(0) != GravityFrames(1) || GravityFrames(len(GravityCurve)+10) != GravityCurve[len(GravityCurve)-1] {
+		t.Error("levels outside the curve do not take its ends")
+	}
+}
+
+func TestMarathonGoal(t *testing.T) {
+	if name := (Marathon{Lines: 150}).Name(); name != "MARATHON 150" {
+		t.Errorf("named %q, want MARATHON 150", name)
+	}
+	if name := (Marathon{}).Name(); name != "MARATHON ENDLESS" {
+		t.Errorf("named %q, want MARATHON ENDLESS", name)
+	}
+
+	// The O piece spawns over the gap and clears both lines of the goal
+	mode := Marathon{StartLevel: 3, Lines: 2}
+	g := NewModeGame(DefaultPieceSet(), 1, mode)
+	grid, err := ParseBoard("####..####\n####..####")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetGrid(grid)
+	g.IncomingType = g.Set.Index("O")
+
+	if g.Level != 3 || g.gravitySpeed != GravityFrames(3) {
+		t.Errorf("started at level %d falling a row every %d frames, want level 3", g.Level, g.gravitySpeed)
+	}
+	stepUntil(t, g, InputDown, func() bool { return g.Finished })
+	if g.Lines != 2 || g.GameOver {
+		t.Errorf("finished after %d lines, topped out %v, want 2 lines", g.Lines, g.GameOver)
+	}
+}
@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MaxPieceSize is the side of the biggest bounding box a piece can rotate in
+const MaxPieceSize = 5
+
+// PieceShape is one rotation state of a piece, indexed [x][y] like the grid
+type PieceShape [MaxPieceSize][MaxPieceSize]GridSquare
+
+// PieceDefinition describes a piece of a set with all its rotation states
+type PieceDefinition struct {
+	Name      string
+	Weight    int
+	Size      int
+	Rotations []PieceShape
+	Masks     []PieceMask // The rotations as bitmasks, in the same order
+}
+
+// PieceSet is the collection of pieces the randomizer picks from
+type PieceSet struct {
+	Name   string
+	Pieces []PieceDefinition
+}
+
+// pieceSetFile mirrors the JSON layout of a piece set definition file.
+// Shapes are rows of '#' (filled) and '.' (empty), top row first.
+type pieceSetFile struct {
+	Name   string      `json:"name"`
+	Pieces []pieceFile `json:"pieces"`
+}
+
+type pieceFile struct {
+	Name      string     `json:"name"`
+	Weight    *int       `json:"weight,omitempty"`
+	Shape     []string   `json:"shape,omitempty"`
+	Rotations [][]string `json:"rotations,omitempty"`
+}
+
+// DefaultPieceSet returns the seven classic tetrominoes
+func DefaultPieceSet() *PieceSet {
+	set, err := ParsePieceSet([]byte(defaultPieceSetJSON))
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// LoadPieceSet reads a piece set definition file
+func LoadPieceSet(path string) (*PieceSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := ParsePieceSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// ParsePieceSet decodes a piece set definition
+func ParsePieceSet(data []byte) (*PieceSet, error) {
+	set := &PieceSet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// UnmarshalJSON decodes a piece set definition. When a piece only gives a
+// shape, its rotation states are generated by turning it inside its box.
+func (s *PieceSet) UnmarshalJSON(data []byte) error {
+	var file pieceSetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if len(file.Pieces) == 0 {
+		return fmt.Errorf("piece set %q has no pieces", file.Name)
+	}
+
+	set := PieceSet{Name: file.Name}
+	total := 0
+
+	for n, p := range file.Pieces {
+		definition := PieceDefinition{Name: p.Name, Weight: 1}
+		if definition.Name == "" {
+			definition.Name = fmt.Sprint(n)
+		}
+		if p.Weight != nil {
+			if *p.Weight < 0 {
+				return fmt.Errorf("piece %s: negative weight", definition.Name)
+			}
+			definition.Weight = *p.Weight
+		}
+
+		rows := p.Rotations
+		if len(rows) == 0 {
+			if len(p.Shape) == 0 {
+				return fmt.Errorf("piece %s: no shape", definition.Name)
+			}
+			rows = [][]string{p.Shape}
+		} else if len(p.Shape) != 0 {
+			return fmt.Errorf("piece %s: both shape and rotations given", definition.Name)
+		}
+
+		for _, r := range rows {
+			shape, size, err := parseShape(r)
+			if err != nil {
+				return fmt.Errorf("piece %s: %w", definition.Name, err)
+			}
+			definition.Size = max(definition.Size, size)
+			definition.Rotations = append(definition.Rotations, shape)
+		}
+
+		// Only a single state given, turn it to get the other three
+		if len(p.Rotations) == 0 {
+			for i := 1; i < 4; i++ {
+				definition.Rotations = append(definition.Rotations, rotateShape(definition.Rotations[i-1], definition.Size))
+			}
+		}
+
+		for _, shape := range definition.Rotations {
+			definition.Masks = append(definition.Masks, shape.Mask())
+		}
+
+		total += definition.Weight
+		set.Pieces = append(set.Pieces, definition)
+	}
+
+	if total == 0 {
+		return fmt.Errorf("piece set %q has no piece with a positive weight", file.Name)
+	}
+
+	*s = set
+	return nil
+}
+
+// MarshalJSON encodes the set as a definition file listing every rotation state
+func (s *PieceSet) MarshalJSON() ([]byte, error) {
+	file := pieceSetFile{Name: s.Name}
+
+	for _, p := range s.Pieces {
+		piece := pieceFile{Name: p.Name, Weight: &p.Weight}
+		for _, shape := range p.Rotations {
+			piece.Rotations = append(piece.Rotations, formatShape(shape, p.Size))
+		}
+		file.Pieces = append(file.Pieces, piece)
+	}
+
+	return json.Marshal(file)
+}
+
+// Pick returns the index of the piece matching a value in [0, total weight)
+func (s *PieceSet) Pick(value int) int {
+	for i, p := range s.Pieces {
+		if value < p.Weight {
+			return i
+		}
+		value -= p.Weight
+	}
+	return len(s.Pieces) - 1
+}
+
+// Index returns the index of the piece with a name, -1 if there is none
+func (s *PieceSet) Index(name string) int {
+	for i, p := range s.Pieces {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// TotalWeight returns the sum of the weights of every piece in the set
+func (s *PieceSet) TotalWeight() int {
+	total := 0
+	for _, p := range s.Pieces {
+		total += p.Weight
+	}
+	return total
+}
+
+// parseShape converts rows of '#' and '.' into a shape and the side of its square box
+func parseShape(rows []string) (PieceShape, int, error) {
+	var shape PieceShape
+
+	size := len(rows)
+	for _, row := range rows {
+		size = max(size, len(row))
+	}
+	if size > MaxPieceSize {
+		return shape, 0, fmt.Errorf("shape is bigger than %dx%d", MaxPieceSize, MaxPieceSize)
+	}
+
+	cells := 0
+	for j, row := range rows {
+		for i, c := range row {
+			switch c {
+			case '#':
+				shape[i][j] = Moving
+				cells++
+			case '.':
+			default:
+				return shape, 0, fmt.Errorf("unexpected character %q in shape", c)
+			}
+		}
+	}
+	if cells == 0 {
+		return shape, 0, fmt.Errorf("shape is empty")
+	}
+
+	return shape, size, nil
+}
+
+// formatShape converts a shape back into rows of '#' and '.'
+func formatShape(shape PieceShape, size int) []string {
+	rows := make([]string, size)
+
+	for j := 0; j < size; j++ {
+		row := make([]byte, size)
+		for i := 0; i < size; i++ {
+			row[i] = '.'
+			if shape[i][j] == Moving {
+				row[i] = '#'
+			}
+		}
+		rows[j] = string(row)
+	}
+
+	return rows
+}
+
+// rotateShape turns a shape a quarter counterclockwise inside its box
+func rotateShape(shape PieceShape, size int) PieceShape {
+	var rotated PieceShape
+
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			rotated[i][j] = shape[size-1-j][i]
+		}
+	}
+
+	return rotated
+}
+
+// Classic tetrominoes laid out in a 4x4 box as in the original game
+const defaultPieceSetJSON = `{
+	"name": "Tetrominoes",
+	"pieces": [
+		{"name": "O", "shape": ["....", ".##.", ".##.", "...."]},
+		{"name": "L", "shape": [".#..", ".#..", ".##.", "...."]},
+		{"name": "J", "shape": ["..#.", "..#.", ".##.", "...."]},
+		{"name": "I", "shape": ["....", "####", "....", "...."]},
+		{"name": "T", "shape": [".#..", ".##.", ".#..", "...."]},
+		{"name": "Z", "shape": ["....", ".##.", "..##", "...."]},
+		{"name": "S", "shape": ["....", "..##", ".##.", "...."]}
+	]
+}`
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Puzzle is a hand-made challenge: a starting grid, a fixed sequence of
+// pieces and a goal. It is solved when the goal is reached and failed when
+// the pieces run out first. Without pieces random ones are dealt, and
+// without a goal the game goes on until the stack tops out, which makes a
+// puzzle a test position as well.
+type Puzzle struct {
+	Title       string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Board       []string   `json:"board"`          // Rows in board notation, only empty and full squares
+	Pieces      []string   `json:"pieces"`         // Names of the pieces dealt, in order
+	Hold        string     `json:"hold,omitempty"` // Name of the piece held at the start
+	Goal        PuzzleGoal `json:"goal"`
+}
+
+// PuzzleGoal lists what solves a puzzle, every condition given must be met
+type PuzzleGoal struct {
+	Lines        int      `json:"lines,omitempty"`        // Lines to clear in total
+	Clear        int      `json:"clear,omitempty"`        // Lines to clear with a single piece, 4 for a tetris
+	PerfectClear bool     `json:"perfectClear,omitempty"` // Leave the grid empty
+	Target       []string `json:"target,omitempty"`       // Bottom rows to build, # filled, . empty and ? either
+}
+
+// Empty reports whether the goal has no condition at all
+func (goal PuzzleGoal) Empty() bool {
+	return goal.Lines <= 0 && goal.Clear <= 0 && !goal.PerfectClear && len(goal.Target) == 0
+}
+
+// LoadPuzzle reads a puzzle file, checking it against the piece set it is played with
+func LoadPuzzle(path string, set *PieceSet) (*Puzzle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := ParsePuzzle(data, set)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// LoadPuzzles reads every puzzle file of a folder, sorted by file name
+func LoadPuzzles(dir string, set *PieceSet) ([]*Puzzle, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	puzzles := make([]*Puzzle, 0, len(paths))
+	for _, path := range paths {
+		p, err := LoadPuzzle(path, set)
+		if err != nil {
+			return nil, err
+		}
+		puzzles = append(puzzles, p)
+	}
+
+	return puzzles, nil
+}
+
+// SavePuzzle writes a puzzle file
+func SavePuzzle(path string, p *Puzzle) error {
+	data, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ParsePuzzle reads a puzzle definition, checking it against a piece set
+func ParsePuzzle(data []byte, set *PieceSet) (*Puzzle, error) {
+	var p Puzzle
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	if err := p.Check(set); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Check reports the first problem keeping a puzzle from being played with a piece set
+func (p *Puzzle) Check(set *PieceSet) error {
+	grid, err := ParseRows(p.Board)
+	if err != nil {
+		return fmt.Errorf("puzzle %q: %w", p.Title, err)
+	}
+	for i := 1; i < GridHorizontalSize-1; i++ {
+		for j := 0; j < GridVerticalSize-1; j++ {
+			if grid[i][j] != Empty && grid[i][j] != Full {
+				return fmt.Errorf("puzzle %q: board can only have empty and full squares", p.Title)
+			}
+		}
+	}
+
+	if err := checkTarget(p.Goal.Target); err != nil {
+		return fmt.Errorf("puzzle %q: target: %w", p.Title, err)
+	}
+
+	if len(p.Pieces) > MaxQueue {
+		return fmt.Errorf("puzzle %q: more than %d pieces", p.Title, MaxQueue)
+	}
+	for _, name := range append([]string{p.Hold}, p.Pieces...) {
+		if name != "" && set.Index(name) < 0 {
+			return fmt.Errorf("puzzle %q: no piece %q in %s", p.Title, name, set.Name)
+		}
+	}
+
+	return nil
+}
+
+// Name of the mode, the title of the puzzle
+func (p *Puzzle) Name() string {
+	return p.Title
+}
+
+// Setup fills the grid and queues the pieces of the puzzle
+func (p *Puzzle) Setup(g *Game) {
+	grid, _ := ParseRows(p.Board)
+	g.SetGrid(grid)
+
+	for _, name := range p.Pieces {
+		g.Queue[g.QueueLength] = g.Set.Index(name)
+		g.QueueLength++
+	}
+	if len(p.Pieces) > 0 {
+		g.QueueOnly = true
+		g.GetRandomPiece()
+	}
+
+	if p.Hold != "" {
+		g.HoldType = g.Set.Index(p.Hold)
+	}
+}
+
+// Update checks the goal every time a piece has settled, failing the puzzle once no piece is left
+func (p *Puzzle) Update(g *Game) {
+	if g.PieceActive || g.LineToDelete || g.Spawned == 0 {
+		return
+	}
+
+	if !p.Goal.Empty() && p.Solved(g) {
+		g.Finished = true
+	} else if g.IncomingType < 0 && g.HoldType < 0 {
+		g.GameOver = true
+	}
+}
+
+// Solved reports whether a game meets every condition of the puzzle goal
+func (p *Puzzle) Solved(g *Game) bool {
+	if g.Lines < p.Goal.Lines {
+		return false
+	}
+
+	if p.Goal.Clear > 0 {
+		cleared := false
+		for lines := p.Goal.Clear; lines <= MaxPieceSize; lines++ {
+			cleared = cleared || g.Clears[lines] > 0
+		}
+		if !cleared {
+			return false
+		}
+	}
+
+	if p.Goal.PerfectClear {
+		for i := 1; i < GridHorizontalSize-1; i++ {
+			for j := 0; j < GridVerticalSize-1; j++ {
+				if g.Grid[i][j] != Empty {
+					return false
+				}
+			}
+		}
+	}
+
+	top := GridVerticalSize - 1 - len(p.Goal.Target)
+	for j, row := range p.Goal.Target {
+		for i, c := range row {
+			filled := g.Grid[i+1][top+j] != Empty
+			if (c == FullChar && !filled) || (c == EmptyChar && filled) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// checkTarget checks the rows of a target, in board notation with ? for squares that can be either
+func checkTarget(rows []string) error {
+	if len(rows) > GridVerticalSize-1 {
+		return fmt.Errorf("%d rows, at most %d fit", len(rows), GridVerticalSize-1)
+	}
+
+	for _, row := range rows {
+		if len(row) != GridHorizontalSize-2 {
+			return fmt.Errorf("row %q is not %d squares wide", row, GridHorizontalSize-2)
+		}
+		if strings.Trim(row, "#.?") != "" {
+			return fmt.Errorf("row %q can only have #, . and ?", row)
+		}
+	}
+
+	return nil
+}
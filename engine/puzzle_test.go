@@ -0,0 +1,64 @@
+package engine
+
+import "testing"
+
+func TestPuzzleFolder(t *testing.T) {
+	puzzles, err := LoadPuzzles("../puzzles", DefaultPieceSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) == 0 {
+		t.Fatal("no puzzles")
+	}
+}
+
+func TestPuzzleGoal(t *testing.T) {
+	tests := []struct {
+		name   string
+		goal   PuzzleGoal
+		solved bool
+	}{
+		{"lines", PuzzleGoal{Lines: 1}, true},
+		{"too many lines", PuzzleGoal{Lines: 2}, false},
+		{"single", PuzzleGoal{Clear: 1}, true},
+		{"tetris", PuzzleGoal{Clear: 4}, false},
+		{"perfect clear", PuzzleGoal{PerfectClear: true}, true},
+		{"target", PuzzleGoal{Target: []string{"??????????"}}, true},
+		{"missed target", PuzzleGoal{Target: []string{"#?????????"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Puzzle{Title: test.name, Board: []string{"###....###"}, Pieces: []string{"I"}, Goal: test.goal}
+			if err := p.Check(DefaultPieceSet()); err != nil {
+				t.Fatal(err)
+			}
+
+			// The only piece drops straight into the gap
+			g := NewModeGame(DefaultPieceSet(), 1, p)
+			for i := 0; i < 1000 && !g.Finished && !g.GameOver; i++ {
+				g.Step(InputDown)
+			}
+
+			if g.Finished != test.solved || g.GameOver == test.solved {
+				t.Errorf("finished %v game over %v, want the puzzle solved to be %v", g.Finished, g.GameOver, test.solved)
+			}
+		})
+	}
+}
+
+func TestPuzzleCheck(t *testing.T) {
+	tests := map[string]Puzzle{
+		"narrow board":  {Board: []string{"###"}},
+		"moving square": {Board: []string{"###@@@@###"}},
+		"unknown piece": {Pieces: []string{"Q"}},
+		"unknown hold":  {Hold: "Q"},
+		"bad target":    {Goal: PuzzleGoal{Target: []string{"###~~~~###"}}},
+	}
+
+	for name, p := range tests {
+		if err := p.Check(DefaultPieceSet()); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
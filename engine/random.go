@@ -0,0 +1,26 @@
+package engine
+
+// Random is a small seeded generator (splitmix64). It is a plain value so
+// copying a Game also copies the exact sequence of pieces still to come.
+type Random struct {
+	state uint64
+}
+
+// NewRandom returns a generator started from seed
+func NewRandom(seed int64) Random {
+	return Random{state: uint64(seed)}
+}
+
+// Uint64 returns the next value of the sequence
+func (r *Random) Uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Intn returns a value in [0, n)
+func (r *Random) Intn(n int) int {
+	return int(r.Uint64() % uint64(n))
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Timing is the set of delays, in frames, that give a game its pace
+type Timing struct {
+	Name           string `json:"name"`
+	LineClearDelay int    `json:"lineClearDelay"` // Frames cleared lines fade before they are removed
+	ARE            int    `json:"are"`            // Frames between a piece locking and the next one spawning
+	LineARE        int    `json:"lineAre"`        // ARE after a clear, counted once the lines are removed
+	DAS            int    `json:"das"`            // Frames a sideways button is held before the piece shifts on its own, 0 for none
+	ARR            int    `json:"arr"`            // Frames between two automatic shifts
+	LockDelay      int    `json:"lockDelay"`      // Frames a landed piece can still move before it locks, 0 to lock when gravity next pulls it
+}
+
+// Timing presets
+var (
+	// ClassicTiming is the pace the game always had: long fading lines, no
+	// entry delay, no automatic shift and no lock delay
+	ClassicTiming = Timing{Name: "CLASSIC", LineClearDelay: FadingTime}
+
+	// NESTiming follows the NES game, with its slow auto shift
+	NESTiming = Timing{Name: "NES", LineClearDelay: 18, ARE: 10, LineARE: 10, DAS: 16, ARR: 6}
+
+	// GuidelineTiming follows the modern guideline games: quick entry,
+	// fast auto shift and half a second to slide a landed piece
+	GuidelineTiming = Timing{Name: "GUIDELINE", LineClearDelay: 20, ARE: 6, LineARE: 6, DAS: 10, ARR: 2, LockDelay: 30}
+
+	// TGMTiming follows the first Tetris The Grand Master at its start
+	TGMTiming = Timing{Name: "TGM", LineClearDelay: 41, ARE: 30, LineARE: 30, DAS: 16, ARR: 1, LockDelay: 30}
+
+	// ZeroDelayTiming removes every delay between pieces, for fast play and training
+	ZeroDelayTiming = Timing{Name: "ZERO DELAY", DAS: 8, ARR: 1, LockDelay: 30}
+)
+
+// Timings lists the presets in the order the menus cycle through them
+var Timings = []Timing{ClassicTiming, NESTiming, GuidelineTiming, TGMTiming, ZeroDelayTiming}
+
+// TimingByName returns the preset with a name, in any case and with dashes or underscores for spaces
+func TimingByName(name string) (Timing, error) {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToUpper(name))
+
+	var names []string
+	for _, t := range Timings {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, strings.ToLower(strings.ReplaceAll(t.Name, " ", "-")))
+	}
+
+	return Timing{}, fmt.Errorf("unknown timing %q, pick from %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,116 @@
+package engine
+
+import "testing"
+
+func TestTimingByName(t *testing.T) {
+	for _, name := range []string{"nes", "Guideline", "TGM", "zero-delay", "zero_delay", "CLASSIC"} {
+		if _, err := TimingByName(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := TimingByName("arcade"); err == nil {
+		t.Error("no error for an unknown timing")
+	}
+}
+
+// stepUntil steps a game with the same input until a condition holds, returning the frame it took
+func stepUntil(t *testing.T, g *Game, input Input, done func() bool) int {
+	t.Helper()
+
+	for i := 0; i < 10000 && !done(); i++ {
+		g.Step(input)
+	}
+	if !done() {
+		t.Fatal("the game never got there")
+	}
+	return g.Frame
+}
+
+func TestLineClearDelayAndARE(t *testing.T) {
+	for _, timing := range Timings {
+		t.Run(timing.Name, func(t *testing.T) {
+			// The I piece spawns flat over the gap and clears the line when dropped
+			g := boardGame(t, "###....###")
+			g.Timing = timing
+			g.IncomingType = g.Set.Index("I")
+
+			locked := stepUntil(t, g, InputDown, func() bool { return g.Pieces == 1 })
+			cleared := stepUntil(t, g, 0, func() bool { return g.Lines == 1 })
+			spawned := stepUntil(t, g, 0, func() bool { return g.Spawned == 2 })
+
+			if got, want := cleared-locked, max(timing.LineClearDelay, 1); got != want {
+				t.Errorf("lines removed %d frames after the lock, want %d", got, want)
+			}
+			if got, want := spawned-cleared, timing.LineARE+1; got != want {
+				t.Errorf("next piece %d frames after the clear, want %d", got, want)
+			}
+
+			// Without a clear, the next piece comes after the ARE
+			locked = stepUntil(t, g, InputDown, func() bool { return g.Pieces == 2 })
+			spawned = stepUntil(t, g, 0, func() bool { return g.Spawned == 3 })
+			if got, want := spawned-locked, timing.ARE+1; got != want {
+				t.Errorf("next piece %d frames after the lock, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestDAS(t *testing.T) {
+	for _, timing := range Timings {
+		t.Run(timing.Name, func(t *testing.T) {
+			g := NewGame(DefaultPieceSet(), 1)
+			g.Timing = timing
+			g.Step(0)
+			x := g.PiecePositionX
+
+			// One shift when the button goes down, then the automatic ones
+			frames := timing.DAS + 2*max(timing.ARR, 1)
+			for i := 0; i < frames; i++ {
+				g.Step(InputRight)
+			}
+
+			want := 1
+			if timing.DAS > 0 {
+				want += 1 + (frames-1-timing.DAS)/max(timing.ARR, 1)
+			}
+			if got := g.PiecePositionX - x; got != want {
+				t.Errorf("moved %d squares holding right %d frames, want %d", got, frames, want)
+			}
+		})
+	}
+}
+
+func TestLockDelay(t *testing.T) {
+	g := boardGame(t, "")
+	g.Timing = GuidelineTiming
+	g.IncomingType = g.Set.Index("O")
+
+	// Dropped to the floor, the piece waits the lock delay before locking
+	landed := stepUntil(t, g, InputDown, func() bool {
+		return g.PieceActive && !g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY+1)
+	})
+	locked := stepUntil(t, g, 0, func() bool { return g.Pieces == 1 })
+
+	if got := locked - landed; got != GuidelineTiming.LockDelay {
+		t.Errorf("locked %d frames after landing, want %d", got, GuidelineTiming.LockDelay)
+	}
+	checkBoard(t, g, "....##....\n....##....")
+}
+
+func TestLockDelaySlide(t *testing.T) {
+	// The O piece lands on a ledge and slides off it before the lock delay runs out
+	g := boardGame(t, "....##....")
+	g.Timing = GuidelineTiming
+	g.IncomingType = g.Set.Index("O")
+
+	stepUntil(t, g, InputDown, func() bool {
+		return g.PieceActive && !g.CanPlace(g.PieceType, g.PieceRotation, g.PiecePositionX, g.PiecePositionY+1)
+	})
+	for i := 0; i < 3; i++ {
+		g.Step(InputRight)
+		g.Step(0)
+	}
+	stepUntil(t, g, InputDown, func() bool { return g.Pieces == 1 })
+
+	checkBoard(t, g, ".......##.\n....##.##.")
+}
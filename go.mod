@@ -2,9 +2,10 @@ module tetris/main
 
 go 1.22.4
 
+require github.com/gen2brain/raylib-go/raylib v0.0.0-20240524074310-a997a44fb95b
+
 require (
 	github.com/ebitengine/purego v0.7.1 // indirect
-	github.com/gen2brain/raylib-go/raylib v0.0.0-20240524074310-a997a44fb95b // indirect
 	golang.org/x/exp v0.0.0-20240613232115-7f521ea00fb8 // indirect
 	golang.org/x/sys v0.21.0 // indirect
 )
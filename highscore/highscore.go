@@ -0,0 +1,80 @@
+// Package highscore keeps the results of finished games in a JSON file, so
+// players can compare a run with their personal best.
+package highscore
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Record is the result of one finished game
+type Record struct {
+	Mode   string    `json:"mode"`   // Name of the mode, with its goal
+	Frames int       `json:"frames"` // Frames played, 60 per second
+	Score  int       `json:"score"`
+	Lines  int       `json:"lines"`
+	Splits []int     `json:"splits,omitempty"` // Frames at which the milestones of the mode were reached
+	Date   time.Time `json:"date"`
+}
+
+// Table is every record of a high score file
+type Table struct {
+	Path    string   `json:"-"`
+	Records []Record `json:"records"`
+}
+
+// Load reads a high score file, a missing file giving an empty table
+func Load(path string) (*Table, error) {
+	t := &Table{Path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return t, err
+	}
+
+	err = json.Unmarshal(data, t)
+	return t, err
+}
+
+// Add appends a record and writes the table back to its file
+func (t *Table) Add(r Record) error {
+	t.Records = append(t.Records, r)
+
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.Path, append(data, '\n'), 0o644)
+}
+
+// Fastest returns the record of a mode played in the fewest frames
+func (t *Table) Fastest(mode string) (Record, bool) {
+	return t.best(mode, func(a, b Record) bool { return a.Frames < b.Frames })
+}
+
+// Highest returns the record of a mode with the highest score
+func (t *Table) Highest(mode string) (Record, bool) {
+	return t.best(mode, func(a, b Record) bool { return a.Score > b.Score })
+}
+
+// best returns the record of a mode that beats all others
+func (t *Table) best(mode string, better func(a, b Record) bool) (Record, bool) {
+	var best Record
+	found := false
+
+	for _, r := range t.Records {
+		if r.Mode == mode && (!found || better(r, best)) {
+			best = r
+			found = true
+		}
+	}
+
+	return best, found
+}
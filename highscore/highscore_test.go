@@ -0,0 +1,42 @@
+package highscore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+
+	table, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := table.Fastest("SPRINT 40"); ok {
+		t.Fatal("missing file has records")
+	}
+
+	for _, r := range []Record{
+		{Mode: "SPRINT 40", Frames: 5000, Score: 900, Splits: []int{1200, 2500, 3700, 5000}},
+		{Mode: "SPRINT 40", Frames: 4000, Score: 700},
+		{Mode: "SPRINT 20", Frames: 1000, Score: 1500},
+	} {
+		if err := table.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Records) != 3 {
+		t.Fatalf("got %d records, want 3", len(table.Records))
+	}
+	if best, _ := table.Fastest("SPRINT 40"); best.Frames != 4000 {
+		t.Errorf("fastest sprint took %d frames, want 4000", best.Frames)
+	}
+	if best, _ := table.Highest("SPRINT 40"); best.Score != 900 || len(best.Splits) != 4 {
+		t.Errorf("highest sprint is %+v", best)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+)
+
+// Size of the layout in squares: the game screens were designed for a
+// ScreenWidth x ScreenHeight window with squares of SquareSize pixels
+const (
+	LayoutColumns = float32(ScreenWidth) / SquareSize
+	LayoutRows    = float32(ScreenHeight) / SquareSize
+)
+
+// MinTextSize is the smallest text drawn, that of the smallest text of the
+// screens in the smallest window. Text shrinks with the rest of the layout so
+// it stays clear of its neighbours and within the window.
+const MinTextSize = 5
+
+// Layout is where the game screens are drawn in the window. It is worked out
+// again every frame from the window size, so the screens grow with the
+// window and stay centred in it when it is resized or made fullscreen.
+type Layout struct {
+	Square float32    // Side of a square of the grid, in whole pixels
+	Scale  float32    // Size of the layout against the window it was designed for
+	Center rl.Vector2 // Middle of the window
+	Grid   rl.Vector2 // Top left corner of the single player grid
+	Side   rl.Vector2 // Incoming piece, lines, score and held piece, right of the grid
+	Status rl.Vector2 // Progress of the mode, left of the grid
+}
+
+// layout is the layout of the current window size
+var layout Layout
+
+// UpdateLayout works the layout out from the current window size
+func UpdateLayout() {
+	width, height := float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight())
+
+	layout.Square = max(float32(math.Floor(float64(min(width/LayoutColumns, height/LayoutRows)))), 1)
+	layout.Scale = layout.Square / SquareSize
+	layout.Center = rl.Vector2{X: width / 2, Y: height / 2}
+
+	// The grid is a little left of the middle to leave room for the side panel
+	layout.Grid = rl.Vector2{
+		X: layout.Center.X - (engine.GridHorizontalSize/2+2.5)*layout.Square,
+		Y: layout.Center.Y - engine.GridVerticalSize*layout.Square/2,
+	}
+	layout.Side = rl.Vector2{X: layout.Grid.X + (engine.GridHorizontalSize+1.5)*layout.Square, Y: layout.Grid.Y + layout.Square}
+	layout.Status = rl.Vector2{X: layout.Grid.X - 10.5*layout.Square, Y: layout.Grid.Y + layout.Square}
+}
+
+// Size scales a length given in pixels of the window the layout was designed for
+func (l *Layout) Size(pixels float32) float32 {
+	return pixels * l.Scale
+}
+
+// Text scales a text size given for the window the layout was designed for
+func (l *Layout) Text(size int32) int32 {
+	return max(int32(float32(size)*l.Scale), MinTextSize)
+}
+
+// DrawCentered draws a line of text centred across the window, its size given for the window the layout was designed for
+func DrawCentered(text string, y float32, size int32, color rl.Color) {
+	size = layout.Text(size)
+	rl.DrawText(text, int32(layout.Center.X)-rl.MeasureText(text, size)/2, int32(y), size, color)
+}
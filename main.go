@@ -1,58 +1,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+	"tetris/main/highscore"
+	"tetris/main/spectate"
 )
 
-// Some Defines
+// Size of the window at start and of a square of the grid in it, the layout scales both with the window
 const (
     SquareSize            = 20
-    GridHorizontalSize    = 12
-    GridVerticalSize      = 20
-    LateralSpeed          = 10
-    TurningSpeed          = 12
-    FastFallAwaitCounter  = 30
-    FadingTime            = 33
     ScreenWidth           = 800
     ScreenHeight          = 450
-    GravitySpeedInitial   = 30
 )
 
-// GridSquare represents the state of a square in the grid
-type GridSquare int
+// GameScreen is the screen currently shown
+type GameScreen int
 
-// Enumeration for GridSquare
+// Enumeration for GameScreen
 const (
-    Empty GridSquare = iota
-    Moving
-    Full
-    Block
-    Fading
+    TitleScreen GameScreen = iota
+    GameplayScreen
+    DemoScreen
+    VersusScreen
+    NetplayScreen
+    PuzzleScreen
+    EditorScreen
 )
 
 // Global Variables
 var (
-    gameOver                 bool
+    screen                   = TitleScreen
     pause                    bool
-    grid                     [GridHorizontalSize][GridVerticalSize]GridSquare
-    piece                    [4][4]GridSquare
-    incomingPiece            [4][4]GridSquare
-    piecePositionX           int
-    piecePositionY           int
-    fadingColor              rl.Color
-    beginPlay                = true
-    pieceActive              bool
-    detection                bool
-    lineToDelete             bool
-    lines                    = 0
-    gravityMovementCounter   int
-    lateralMovementCounter   int
-    turnMovementCounter      int
-    fastFallMovementCounter  int
-    fadeLineCounter          int
-    gravitySpeed             = GravitySpeedInitial
+    pieceSet                 *engine.PieceSet
+    mode                     engine.Mode
+    timing                   engine.Timing
+    defaultTiming            engine.Timing
+    cheeseLines              int
+    startLevel               int
+    marathonLines            int
+    sprintLines              int
+    ultraTime                time.Duration
+    game                     *engine.Game
+    bot                      *ai.Player
+    autoplay                 bool
+    attractDelay             float64
+    garbageDelay             int
+    spectators               *spectate.Server
+    targetFPS                int
+    clock                    engine.Clock
+    steps                    int            // Steps of the games to run in the frame being drawn
+    playerInputs             [2]InputLatch  // Buttons of the local players waiting for the next step
 )
 
 //------------------------------------------------------------------------------------
@@ -60,550 +66,426 @@ var (
 //------------------------------------------------------------------------------------
 
 func main() {
+    // Headless subcommands never open the window
+    if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+        os.Exit(RunCommand(os.Args[1], os.Args[2:]))
+    }
+
+    piecesPath := flag.String("pieces", "", "load the pieces from a piece set definition file")
+    weightsPath := flag.String("weights", "", "load the AI weights from a file written by tune")
+    flag.BoolVar(&autoplay, "ai", false, "let the computer play")
+    scoresPath := flag.String("scores", "scores.json", "file keeping the results of finished games")
+    puzzlesPath := flag.String("puzzles", "puzzles", "folder of the puzzle files")
+    flag.StringVar(&editorPath, "board", "board.json", "file the board editor saves to and loads from")
+    flag.IntVar(&startLevel, "level", 1, "level marathon starts at")
+    flag.IntVar(&marathonLines, "marathon-lines", 150, "lines to clear in marathon")
+    flag.IntVar(&sprintLines, "sprint-lines", 40, "lines to clear in sprint")
+    flag.DurationVar(&ultraTime, "ultra-time", 2*time.Minute, "time limit of ultra")
+    flag.IntVar(&cheeseLines, "cheese-lines", 10, "garbage lines to dig through in cheese race")
+    timingName := flag.String("timing", "classic", "timing profile of every mode until another is picked on the title screen: classic, nes, guideline, tgm or zero-delay")
+    flag.IntVar(&garbageDelay, "garbage-delay", 60, "frames garbage waits before entering the grid in versus")
+    host := flag.String("host", "", "host a networked versus match on this address, such as :7000")
+    join := flag.String("join", "", "join the networked versus match hosted at this address")
+    spectateAddress := flag.String("spectate", "", "stream the game to browsers on this address, such as 127.0.0.1:8080")
+    inputDelay := flag.Int("input-delay", 3, "frames between pressing a key and playing it in networked matches")
+    flag.Float64Var(&attractDelay, "attract", 10, "seconds idle on the title screen before the demo starts, 0 to disable")
+    effects := flag.String("effects", strings.Join(effectNames, ","), "line clear effects to show, any of "+strings.Join(effectNames, ", ")+" or none")
+    reducedMotion := flag.Bool("reduced-motion", false, "no particles, shaking or blinking, steady flashes and score text")
+    flag.IntVar(&targetFPS, "fps", 0, "frames drawn per second, 0 to follow the monitor; the game always runs at 60 steps per second")
+    sounds := flag.Float64("sound-volume", 0.8, "volume of the sound effects, from 0 to 1, [ and ] change it")
+    music := flag.Float64("music-volume", 0.5, "volume of the music, from 0 to 1, - and = change it")
+    flag.BoolVar(&muted, "mute", false, "start with the sound muted, [M] toggles it")
+    flag.Parse()
+
+    set, err := LoadPieceSetFlag(*piecesPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    pieceSet = set
+
+    defaultTiming, err = engine.TimingByName(*timingName)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    timing = defaultTiming
+
+    effectsConfig, err = ParseEffects(*effects)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    effectsConfig.ReducedMotion = *reducedMotion
+
+    if *sounds < 0 || *sounds > 1 || *music < 0 || *music > 1 {
+        fmt.Fprintln(os.Stderr, "volumes go from 0 to 1")
+        os.Exit(1)
+    }
+    soundVolume, musicVolume = float32(*sounds), float32(*music)
+
+    weights, err := LoadWeightsFlag(*weightsPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    bot = ai.NewPlayer(weights)
+
+    records, err = highscore.Load(*scoresPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    puzzles, err = engine.LoadPuzzles(*puzzlesPath, pieceSet)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    if *spectateAddress != "" {
+        spectators = spectate.NewServer()
+        address, err := spectators.ListenAndServe(*spectateAddress)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Fprintf(os.Stderr, "spectators can watch on http://%s/\n", address)
+    }
+
+    if *host != "" || *join != "" {
+        if err := ConnectNetplay(*host, *join, *inputDelay); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+    }
+
+    // Frames are drawn as fast as the monitor shows them, the clock decides how many steps each one runs
+    rl.SetConfigFlags(rl.FlagVsyncHint | rl.FlagWindowResizable)
     rl.InitWindow(ScreenWidth, ScreenHeight, "Tetris in Go")
-  
+    rl.SetWindowMinSize(ScreenWidth/2, ScreenHeight/2)
+    InitAudio()
 
-    InitGame()
-	rl.SetTargetFPS(60);
+    if screen != NetplayScreen {
+        InitTitle()
+    }
+	rl.SetTargetFPS(int32(targetFPS));
 
     for !rl.WindowShouldClose() {
         UpdateDrawFrame()
     }
+    CloseAudio()
 	rl.CloseWindow();
 }
 
 
 // InitGame initializes the game
 func InitGame() {
-    // Initialize game statistics
-    lines = 0
-
-    fadingColor = rl.Gray
-
-    piecePositionX = 0
-    piecePositionY = 0
+    if mode != nil {
+        game = engine.NewModeGame(pieceSet, time.Now().UnixNano(), mode)
+    } else {
+        game = engine.NewGame(pieceSet, time.Now().UnixNano())
+    }
+    game.Timing = timing
 
     pause = false
+    gameEffects = Effects{}
+    gameSounds = SoundWatcher{}
 
-    beginPlay = true
-    pieceActive = false
-    detection = false
-    lineToDelete = false
-
-    // Counters
-    gravityMovementCounter = 0
-    lateralMovementCounter = 0
-    turnMovementCounter = 0
-    fastFallMovementCounter = 0
-
-    fadeLineCounter = 0
-    gravitySpeed = GravitySpeedInitial
-
-    // Initialize grid matrices
-    for i := 0; i < GridHorizontalSize; i++ {
-        for j := 0; j < GridVerticalSize; j++ {
-            if j == GridVerticalSize-1 || i == 0 || i == GridHorizontalSize-1 {
-                grid[i][j] = Block
-            } else {
-                grid[i][j] = Empty
-            }
-        }
-    }
-
-    // Initialize incoming piece matrices
-    for i := 0; i < 4; i++ {
-        for j := 0; j < 4; j++ {
-            incomingPiece[i][j] = Empty
-        }
-    }
+    bot.Reset()
+    LoadPersonalBest()
 }
 
 // UpdateGame updates the game logic for one frame
 func UpdateGame() {
-    if !gameOver {
+    if screen == DemoScreen {
+        UpdateDemo()
+        return
+    }
+
+    if PlayingEditor() && rl.IsKeyPressed(rl.KeyBackspace) {
+        InitEditor()
+        return
+    }
+
+    if !game.GameOver && !game.Finished {
         if rl.IsKeyPressed(rl.KeyP) {
             pause = !pause
         }
 
         if !pause {
-            if !lineToDelete {
-                if !pieceActive {
-                    // Get another piece
-                    pieceActive = CreatePiece()
-
-                    // We leave a little time before starting the fast falling down
-                    fastFallMovementCounter = 0
-                } else { // Piece falling
-                    // Counters update
-                    fastFallMovementCounter++
-                    gravityMovementCounter++
-                    lateralMovementCounter++
-                    turnMovementCounter++
-
-                    // We make sure to move if we've pressed the key this frame
-                    if rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressed(rl.KeyRight) {
-                        lateralMovementCounter = LateralSpeed
-                    }
-                    if rl.IsKeyPressed(rl.KeyUp) {
-                        turnMovementCounter = TurningSpeed
-                    }
-
-                    // Fall down
-                    if rl.IsKeyDown(rl.KeyDown) && (fastFallMovementCounter >= FastFallAwaitCounter) {
-                        // We make sure the piece is going to fall this frame
-                        gravityMovementCounter += gravitySpeed
-                    }
-
-                    if gravityMovementCounter >= gravitySpeed {
-                        // Basic falling movement
-                        CheckDetection(&detection)
-
-                        // Check if the piece has collided with another piece or with the boundings
-                        ResolveFallingMovement(&detection, &pieceActive)
-
-                        // Check if we fulfilled a line and if so, erase the line and pull down the lines above
-                        CheckCompletion(&lineToDelete)
-
-                        gravityMovementCounter = 0
-                    }
-
-                    // Move laterally at player's will
-                    if lateralMovementCounter >= LateralSpeed {
-                        // Update the lateral movement and if success, reset the lateral counter
-                        if ResolveLateralMovement() {
-                            lateralMovementCounter = 0
-                        }
-                    }
-
-                    // Turn the piece at player's will
-                    if turnMovementCounter >= TurningSpeed {
-                        // Update the turning movement and reset the turning counter
-                        if ResolveTurnMovement() {
-                            turnMovementCounter = 0
-                        }
-                    }
-                }
+            playerInputs[0].Hold(ReadInput(SinglePlayerKeys))
 
-                // Game over logic
-                for j := 0; j < 2; j++ {
-                    for i := 1; i < GridHorizontalSize-1; i++ {
-                        if grid[i][j] == Full {
-                            gameOver = true
-                        }
-                    }
-                }
-            } else {
-                // Animation when deleting lines
-                fadeLineCounter++
-
-                if fadeLineCounter%8 < 4 {
-                    fadingColor = rl.Maroon
+            for i := 0; i < steps && !game.GameOver && !game.Finished; i++ {
+                if autoplay {
+                    game.Step(bot.Next(game))
                 } else {
-                    fadingColor = rl.Gray
+                    game.Step(playerInputs[0].Take())
                 }
+                gameEffects.Observe(game)
+                gameSounds.Observe(game)
+                PublishSpectators()
+            }
+            gameEffects.Update(rl.GetFrameTime())
 
-                if fadeLineCounter >= FadingTime {
-                    deletedLines := DeleteCompleteLines()
-                    fadeLineCounter = 0
-                    lineToDelete = false
-
-                    lines += deletedLines
-                }
+            if game.Finished || game.GameOver {
+                SaveRecord()
             }
         }
     } else {
         if rl.IsKeyPressed(rl.KeyEnter) {
             InitGame()
-            gameOver = false
         }
-    }
-}
 
-// DrawGame draws the game for one frame
-func DrawGame() {
-    rl.BeginDrawing()
-
-    rl.ClearBackground(rl.RayWhite)
-
-    if !gameOver {
-        // Draw gameplay area
-        offset := rl.Vector2{
-            X: float32(ScreenWidth)/2 - (GridHorizontalSize*SquareSize/2) - 50,
-            Y: float32(ScreenHeight)/2 - ((GridVerticalSize-1)*SquareSize/2) + SquareSize*2,
+        if _, ok := game.Mode.(*engine.Puzzle); ok && !PlayingEditor() && rl.IsKeyPressed(rl.KeyBackspace) {
+            InitPuzzles()
         }
+    }
+}
 
-        offset.Y -= 50 // NOTE: Hardcoded position!
-
-        controller := offset.X
-
-        for j := 0; j < GridVerticalSize; j++ {
-            for i := 0; i < GridHorizontalSize; i++ {
-                // Draw each square of the grid
-                switch grid[i][j] {
-                case Empty:
-                    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X+SquareSize), int32(offset.Y), rl.LightGray)
-                    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X), int32(offset.Y+SquareSize), rl.LightGray)
-                    rl.DrawLine(int32(offset.X+SquareSize), int32(offset.Y), int32(offset.X+SquareSize), int32(offset.Y+SquareSize), rl.LightGray)
-                    rl.DrawLine(int32(offset.X), int32(offset.Y+SquareSize), int32(offset.X+SquareSize), int32(offset.Y+SquareSize), rl.LightGray)
-                case Full:
-                    rl.DrawRectangle(int32(offset.X), int32(offset.Y), SquareSize, SquareSize, rl.Black)
-                case Moving:
-                    rl.DrawRectangle(int32(offset.X), int32(offset.Y), SquareSize, SquareSize, rl.Black)
-                case Block:
-                    rl.DrawRectangle(int32(offset.X), int32(offset.Y), SquareSize, SquareSize, rl.LightGray)
-                case Fading:
-                    rl.DrawRectangle(int32(offset.X), int32(offset.Y), SquareSize, SquareSize, fadingColor)
-                }
-
-                offset.X += SquareSize
-            }
-
-            offset.X = controller
-            offset.Y += SquareSize
-        }
-
-        // Draw incoming piece (hardcoded)
-        offset.X = 500
-        offset.Y = 45
-
-        controler := offset.X
-
-        for j := 0; j < 4; j++ {
-            for i := 0; i < 4; i++ {
-                if incomingPiece[i][j] == Empty {
-                    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X+SquareSize), int32(offset.Y), rl.LightGray)
-                    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X), int32(offset.Y+SquareSize), rl.LightGray)
-                    rl.DrawLine(int32(offset.X+SquareSize), int32(offset.Y), int32(offset.X+SquareSize), int32(offset.Y+SquareSize), rl.LightGray)
-                    rl.DrawLine(int32(offset.X), int32(offset.Y+SquareSize), int32(offset.X+SquareSize), int32(offset.Y+SquareSize), rl.LightGray)
-                } else if incomingPiece[i][j] == Moving {
-                    rl.DrawRectangle(int32(offset.X), int32(offset.Y), SquareSize, SquareSize, rl.Black)
-                }
-
-                offset.X += SquareSize
-            }
+// KeyMap binds keyboard keys to the game buttons
+type KeyMap struct {
+    Left, Right, Rotate, Down, Hold int32
+}
 
-            offset.X = controler
-            offset.Y += SquareSize
-        }
+// Key maps of the single player game and both versus players
+var (
+    SinglePlayerKeys = KeyMap{rl.KeyLeft, rl.KeyRight, rl.KeyUp, rl.KeyDown, rl.KeyC}
+    PlayerOneKeys    = KeyMap{rl.KeyA, rl.KeyD, rl.KeyW, rl.KeyS, rl.KeyLeftShift}
+    PlayerTwoKeys    = KeyMap{rl.KeyLeft, rl.KeyRight, rl.KeyUp, rl.KeyDown, rl.KeyRightShift}
+)
 
-        rl.DrawText("INCOMING:", int32(offset.X), int32(offset.Y-100), 10, rl.Gray)
-        rl.DrawText(fmt.Sprintf("LINES:      %04d", lines) , int32(offset.X), int32(offset.Y+20), 10, rl.Gray)
+// ReadInput collects the game buttons held down this frame
+func ReadInput(keys KeyMap) engine.Input {
+    var input engine.Input
 
-        if pause {
-            rl.DrawText("GAME PAUSED", int32(ScreenWidth)/2-rl.MeasureText("GAME PAUSED", 40)/2, int32(ScreenWidth)/2-40, 40, rl.Gray)
-        }
-    } else {
-        rl.DrawText("PRESS [ENTER] TO PLAY AGAIN", int32(rl.GetScreenWidth())/2-rl.MeasureText("PRESS [ENTER] TO PLAY AGAIN", 20)/2, int32(rl.GetScreenHeight())/2-50, 20, rl.Gray)
+    if rl.IsKeyDown(keys.Left) {
+        input |= engine.InputLeft
+    }
+    if rl.IsKeyDown(keys.Right) {
+        input |= engine.InputRight
+    }
+    if rl.IsKeyDown(keys.Rotate) {
+        input |= engine.InputRotate
+    }
+    if rl.IsKeyDown(keys.Down) {
+        input |= engine.InputDown
+    }
+    if rl.IsKeyDown(keys.Hold) {
+        input |= engine.InputHold
     }
 
-    rl.EndDrawing()
+    return input
 }
 
-
-// UpdateDrawFrame updates the game state and draws one frame
-func UpdateDrawFrame() {
-    UpdateGame()
-    DrawGame()
+// InputLatch keeps the buttons held in the frames drawn between two steps,
+// so that a tap shorter than a step still reaches the game
+type InputLatch struct {
+    held    engine.Input
+    pending engine.Input
 }
 
-// CreatePiece initializes a new piece and places it at the top of the grid
-func CreatePiece() bool {
-    piecePositionX = (GridHorizontalSize - 4) / 2
-    piecePositionY = 0
+// Hold records the buttons held in a drawn frame
+func (l *InputLatch) Hold(input engine.Input) {
+    l.held = input
+    l.pending |= input
+}
 
-    // If the game is starting and you are going to create the first piece, we create an extra one
-    if beginPlay {
-        GetRandomPiece()
-        beginPlay = false
-    }
+// Take returns the buttons of the next step: those held in any frame drawn since the last step
+func (l *InputLatch) Take() engine.Input {
+    input := l.pending
+    l.pending = l.held
+    return input
+}
 
-    // We assign the incoming piece to the actual piece
-    for i := 0; i < 4; i++ {
-        for j := 0; j < 4; j++ {
-            piece[i][j] = incomingPiece[i][j]
-        }
-    }
+// DrawGame draws the game for one frame
+func DrawGame() {
+    rl.BeginDrawing()
 
-    // We assign a random piece to the incoming one
-    GetRandomPiece()
+    rl.ClearBackground(rl.RayWhite)
 
-    // Assign the piece to the grid
-    for i := piecePositionX; i < piecePositionX+4; i++ {
-        for j := 0; j < 4; j++ {
-            if piece[i-piecePositionX][j] == Moving {
-                grid[i][j] = Moving
-            }
+    if !game.GameOver && !game.Finished {
+        // Draw gameplay area
+        shaken := rl.Vector2Add(layout.Grid, gameEffects.Shake())
+        DrawGrid(game, shaken)
+        gameEffects.Draw(shaken)
+
+        // Draw incoming piece right of the grid
+        offset := layout.Side
+        preview := engine.MaxPieceSize * layout.Square
+
+        if game.IncomingType >= 0 {
+            DrawPiecePreview(game.Set.Pieces[game.IncomingType].Rotations[0], offset)
+        } else {
+            DrawPiecePreview(engine.PieceShape{}, offset)
         }
-    }
 
-    return true
-}
+        rl.DrawText("INCOMING:", int32(offset.X), int32(offset.Y-layout.Size(20)), layout.Text(10), rl.Gray)
+        rl.DrawText(fmt.Sprintf("LINES:      %04d", game.Lines) , int32(offset.X), int32(offset.Y+preview+layout.Size(20)), layout.Text(10), rl.Gray)
+        rl.DrawText(fmt.Sprintf("SCORE:  %08d", game.Score) , int32(offset.X), int32(offset.Y+preview+layout.Size(35)), layout.Text(10), rl.Gray)
 
+        // Draw the progress of the mode left of the grid
+        DrawModeStatus(game, layout.Status)
+        DrawCountdown(game)
 
-// GetRandomPiece generates a random piece and assigns it to the incomingPiece variable
-func GetRandomPiece() {
-    random := rl.GetRandomValue(0, 6) // Assuming rl is a package that provides GetRandomValue
+        // Draw held piece under the incoming one
+        offset.Y += preview + layout.Size(80)
 
-    // Reset incomingPiece to Empty
-    for i := 0; i < 4; i++ {
-        for j := 0; j < 4; j++ {
-            incomingPiece[i][j] = Empty
+        rl.DrawText("HOLD:", int32(offset.X), int32(offset.Y-layout.Size(20)), layout.Text(10), rl.Gray)
+        if game.HoldType >= 0 {
+            DrawPiecePreview(game.Set.Pieces[game.HoldType].Rotations[0], offset)
+        } else {
+            DrawPiecePreview(engine.PieceShape{}, offset)
         }
-    }
 
-    // Assign a new shape to incomingPiece based on the random value
-    switch random {
-    case 0:
-        // Cube
-        incomingPiece[1][1] = Moving
-        incomingPiece[2][1] = Moving
-        incomingPiece[1][2] = Moving
-        incomingPiece[2][2] = Moving
-    case 1:
-        // L
-        incomingPiece[1][0] = Moving
-        incomingPiece[1][1] = Moving
-        incomingPiece[1][2] = Moving
-        incomingPiece[2][2] = Moving
-    case 2:
-        // L inversa
-        incomingPiece[1][2] = Moving
-        incomingPiece[2][0] = Moving
-        incomingPiece[2][1] = Moving
-        incomingPiece[2][2] = Moving
-    case 3:
-        // Recta
-        incomingPiece[0][1] = Moving
-        incomingPiece[1][1] = Moving
-        incomingPiece[2][1] = Moving
-        incomingPiece[3][1] = Moving
-    case 4:
-        // Creu tallada
-        incomingPiece[1][0] = Moving
-        incomingPiece[1][1] = Moving
-        incomingPiece[1][2] = Moving
-        incomingPiece[2][1] = Moving
-    case 5:
-        // S
-        incomingPiece[1][1] = Moving
-        incomingPiece[2][1] = Moving
-        incomingPiece[2][2] = Moving
-        incomingPiece[3][2] = Moving
-    case 6:
-        // S inversa
-        incomingPiece[1][2] = Moving
-        incomingPiece[2][2] = Moving
-        incomingPiece[2][1] = Moving
-        incomingPiece[3][1] = Moving
-    }
-}
+        if screen == DemoScreen {
+            DrawDemoOverlay()
+        }
 
-// ResolveFallingMovement checks if the current piece should stop Moving (if it has landed) or continue falling.
-func ResolveFallingMovement(detection *bool, pieceActive *bool) {
-    if *detection {
-        // If we finished Moving this piece, we stop it
-        for j := GridVerticalSize - 2; j >= 0; j-- {
-            for i := 1; i < GridHorizontalSize-1; i++ {
-                if grid[i][j] == Moving {
-                    grid[i][j] = Full
-                    *detection = false
-                    *pieceActive = false
-                }
-            }
+        if pause {
+            rl.DrawText("GAME PAUSED", int32(layout.Center.X)-rl.MeasureText("GAME PAUSED", layout.Text(40))/2, int32(layout.Center.Y-layout.Size(40)), layout.Text(40), rl.Gray)
         }
     } else {
-        // We move down the piece
-        for j := GridVerticalSize - 2; j >= 0; j-- { // Adjusted loop to prevent index out of range
-            for i := 1; i < GridHorizontalSize-1; i++ {
-                if grid[i][j] == Moving {
-                    grid[i][j+1] = Moving
-                    grid[i][j] = Empty
-                }
-            }
+        promptY := layout.Center.Y - layout.Size(50)
+        if ShowResults(game) {
+            DrawResults(game)
+            promptY = layout.Center.Y + layout.Size(175)
         }
-
-        piecePositionY++
+        DrawCentered("PRESS [ENTER] TO PLAY AGAIN", promptY, 20, rl.Gray)
     }
+
+    rl.EndDrawing()
 }
 
-// ResolveLateralMovement checks and performs lateral movement of the current piece, returning true if a collision occurs.
-func ResolveLateralMovement() bool {
-    collision := false
-
-    // Piece movement
-    if rl.IsKeyPressed(rl.KeyLeft) { // Move left
-        // Check if it is possible to move to the left
-        for j := GridVerticalSize - 2; j >= 0; j-- {
-            for i := 1; i < GridHorizontalSize-1; i++ {
-                if grid[i][j] == Moving {
-                    // Check if we are touching the left wall or we have a full square at the left
-                    if i-1 == 0 || grid[i-1][j] == Full {
-                        collision = true
-                    }
-                }
-            }
-        }
+// DrawGrid draws the grid of a game with its top left corner at offset
+func DrawGrid(g *engine.Game, offset rl.Vector2) {
+    // Lines being deleted blink while they fade, unless motion is reduced
+    fadingColor := rl.Gray
+    if g.FadeLineCounter%8 < 4 || effectsConfig.ReducedMotion {
+        fadingColor = rl.Maroon
+    }
 
-        // If able, move left
-        if !collision {
-            for j := GridVerticalSize - 2; j >= 0; j-- {
-                for i := 1; i < GridHorizontalSize-1; i++ {
-                    if grid[i][j] == Moving {
-                        grid[i-1][j] = Moving
-                        grid[i][j] = Empty
-                    }
-                }
-            }
-            piecePositionX--
-        }
-    } else if rl.IsKeyPressed(rl.KeyRight) { // Move right
-        // Check if it is possible to move to the right
-        for j := GridVerticalSize - 2; j >= 0; j-- {
-            for i := 1; i < GridHorizontalSize-1; i++ {
-                if grid[i][j] == Moving {
-                    // Check if we are touching the right wall or we have a full square at the right
-                    if i+1 == GridHorizontalSize-1 || grid[i+1][j] == Full {
-                        collision = true
-                    }
-                }
+    controller := offset.X
+
+    // The active piece is drawn in on a copy of the grid
+    board := g.Board()
+
+    for j := 0; j < engine.GridVerticalSize; j++ {
+        for i := 0; i < engine.GridHorizontalSize; i++ {
+            // Draw each square of the grid
+            switch board[i][j] {
+            case engine.Empty:
+                DrawEmptySquare(offset)
+            case engine.Full:
+                DrawSquare(offset, rl.Black)
+            case engine.Moving:
+                DrawSquare(offset, rl.Black)
+            case engine.Block:
+                DrawSquare(offset, rl.LightGray)
+            case engine.Fading:
+                DrawSquare(offset, fadingColor)
             }
-        }
 
-        // If able, move right
-        if !collision {
-            for j := GridVerticalSize - 2; j >= 0; j-- {
-                for i := GridHorizontalSize - 1; i >= 1; i-- {
-                    if grid[i][j] == Moving {
-                        grid[i+1][j] = Moving
-                        grid[i][j] = Empty
-                    }
-                }
-            }
-            piecePositionX++
+            offset.X += layout.Square
         }
+
+        offset.X = controller
+        offset.Y += layout.Square
     }
 
-    return collision
+    // Incoming garbage stacks up along the left wall
+    if rows := min(g.PendingGarbageRows(), engine.GridVerticalSize-1); rows > 0 {
+        bottom := offset.Y - layout.Square
+        height := float32(rows) * layout.Square
+        rl.DrawRectangleV(rl.Vector2{X: controller + layout.Square/4, Y: bottom - height}, rl.Vector2{X: layout.Square / 2, Y: height}, rl.Red)
+    }
 }
 
-// ResolveTurnMovement checks if the UP key is pressed and rotates the piece if possible.
-func ResolveTurnMovement() bool {
-    // Input for turning the piece
-    if rl.IsKeyDown(rl.KeyUp) {
-        var aux GridSquare
-        checker := false
-
-        // Check all turning possibilities
-        conditions := []bool{
-            grid[piecePositionX+3][piecePositionY] == Moving && grid[piecePositionX][piecePositionY] != Empty && grid[piecePositionX][piecePositionY] != Moving,
-            grid[piecePositionX+3][piecePositionY+3] == Moving && grid[piecePositionX+3][piecePositionY] != Empty && grid[piecePositionX+3][piecePositionY] != Moving,
-            grid[piecePositionX][piecePositionY+3] == Moving && grid[piecePositionX+3][piecePositionY+3] != Empty && grid[piecePositionX+3][piecePositionY+3] != Moving,
-            // Add other conditions following the same pattern
-        }
+// DrawPiecePreview draws a piece in its box with the top left corner at offset
+func DrawPiecePreview(shape engine.PieceShape, offset rl.Vector2) {
+    controller := offset.X
 
-        for _, condition := range conditions {
-            if condition {
-                checker = true
-                break
+    for j := 0; j < engine.MaxPieceSize; j++ {
+        for i := 0; i < engine.MaxPieceSize; i++ {
+            if shape[i][j] == engine.Empty {
+                DrawEmptySquare(offset)
+            } else if shape[i][j] == engine.Moving {
+                DrawSquare(offset, rl.Black)
             }
-        }
 
-        if !checker {
-            // Rotate the piece
-            aux = piece[0][0]
-            piece[0][0], piece[3][0], piece[3][3], piece[0][3] = piece[3][0], piece[3][3], piece[0][3], aux
-            aux = piece[1][0]
-            piece[1][0], piece[3][1], piece[2][3], piece[0][2] = piece[3][1], piece[2][3], piece[0][2], aux
-            aux = piece[2][0]
-            piece[2][0], piece[3][2], piece[1][3], piece[0][1] = piece[3][2], piece[1][3], piece[0][1], aux
-            aux = piece[1][1]
-            piece[1][1], piece[2][1], piece[2][2], piece[1][2] = piece[2][1], piece[2][2], piece[1][2], aux
+            offset.X += layout.Square
         }
 
-        // Clear the Moving piece from the grid
-        for j := GridVerticalSize - 2; j >= 0; j-- {
-            for i := 1; i < GridHorizontalSize - 1; i++ {
-                if grid[i][j] == Moving {
-                    grid[i][j] = Empty
-                }
-            }
-        }
-
-        // Place the piece in the new position
-        for i := piecePositionX; i < piecePositionX+4; i++ {
-            for j := piecePositionY; j < piecePositionY+4; j++ {
-                if piece[i-piecePositionX][j-piecePositionY] == Moving {
-                    grid[i][j] = Moving
-                }
-            }
-        }
-
-        return true
+        offset.X = controller
+        offset.Y += layout.Square
     }
+}
 
-    return false
+// DrawSquare draws a filled square of the grid with its top left corner at offset
+func DrawSquare(offset rl.Vector2, color rl.Color) {
+    rl.DrawRectangle(int32(offset.X), int32(offset.Y), int32(layout.Square), int32(layout.Square), color)
 }
 
+// DrawEmptySquare draws the outline of an empty square
+func DrawEmptySquare(offset rl.Vector2) {
+    size := layout.Square
 
-// CheckDetection checks for detection in a grid.
-func CheckDetection(detection *bool) {
-    for j := GridVerticalSize - 2; j >= 0; j-- {
-        for i := 1; i < GridHorizontalSize-1; i++ {
-            if (grid[i][j] == Moving) && (grid[i][j+1] == Full || grid[i][j+1] == Block) {
-                *detection = true
-            }
-        }
-    }
+    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X+size), int32(offset.Y), rl.LightGray)
+    rl.DrawLine(int32(offset.X), int32(offset.Y), int32(offset.X), int32(offset.Y+size), rl.LightGray)
+    rl.DrawLine(int32(offset.X+size), int32(offset.Y), int32(offset.X+size), int32(offset.Y+size), rl.LightGray)
+    rl.DrawLine(int32(offset.X), int32(offset.Y+size), int32(offset.X+size), int32(offset.Y+size), rl.LightGray)
 }
 
-// CheckCompletion checks each line of the grid to see if it's completely filled.
-func CheckCompletion(lineToDelete *bool) {
-    for j := GridVerticalSize - 2; j >= 0; j-- {
-        calculator := 0
-        for i := 1; i < GridHorizontalSize-1; i++ {
-            if grid[i][j] == Full {
-                calculator++
-            }
 
-            if calculator == GridHorizontalSize-2 {
-                *lineToDelete = true
-                // Reset calculator for the next line
-                calculator = 0
+// UpdateDrawFrame updates the game state and draws one frame
+func UpdateDrawFrame() {
+    steps = clock.Advance(time.Duration(float64(rl.GetFrameTime()) * float64(time.Second)))
 
-                // Mark the completed line for deletion
-                for z := 1; z < GridHorizontalSize-1; z++ {
-                    grid[z][j] = Fading
-                }
-            }
-        }
+    if rl.IsKeyPressed(rl.KeyF11) {
+        rl.ToggleBorderlessWindowed()
+    }
+    UpdateLayout()
+
+    switch screen {
+    case TitleScreen:
+        UpdateTitle()
+        DrawTitle()
+    case GameplayScreen, DemoScreen:
+        UpdateGame()
+        DrawGame()
+    case VersusScreen:
+        UpdateVersus()
+        DrawVersus()
+    case NetplayScreen:
+        UpdateNetplay()
+        DrawNetplay()
+    case PuzzleScreen:
+        UpdatePuzzles()
+        DrawPuzzles()
+    case EditorScreen:
+        UpdateEditor()
+        DrawEditor()
     }
-}
-
-// DeleteCompleteLines goes through the grid and deletes any lines marked as complete.
-func DeleteCompleteLines() int {
-    deletedLines := 0
-
-    for j := GridVerticalSize - 2; j >= 0; j-- {
-        for grid[1][j] == Fading {
-            // Clear the line
-            for i := 1; i < GridHorizontalSize-1; i++ {
-                grid[i][j] = Empty
-            }
 
-            // Move all lines above down
-            for j2 := j - 1; j2 >= 0; j2-- {
-                for i2 := 1; i2 < GridHorizontalSize-1; i2++ {
-                    if grid[i2][j2] == Full || grid[i2][j2] == Fading {
-                        grid[i2][j2+1] = grid[i2][j2]
-                        grid[i2][j2] = Empty
-                    }
-                }
-            }
+    UpdateAudioKeys()
+    UpdateMusic()
+    PublishSpectators()
+}
 
-            deletedLines++
-        }
+// PublishSpectators sends the games on screen to the spectator server, if
+// any. It runs after every step, and once a frame for the games that did not
+// step, such as a game just started.
+func PublishSpectators() {
+    if spectators == nil {
+        return
     }
 
-    return deletedLines
+    switch screen {
+    case GameplayScreen, DemoScreen:
+        spectators.Publish(0, game)
+        spectators.Remove(1)
+    case VersusScreen, NetplayScreen:
+        spectators.Publish(0, match.Players[0])
+        spectators.Publish(1, match.Players[1])
+    }
 }
-
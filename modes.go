@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+	"tetris/main/highscore"
+)
+
+var (
+	records         *highscore.Table // Results of the finished games
+	personalBest    highscore.Record // Best result of the mode being played, before this game
+	hasPersonalBest bool
+	recorded        bool // The result of the game has been saved
+)
+
+// LoadPersonalBest looks up the best result of the mode of the new game
+func LoadPersonalBest() {
+	recorded = false
+	hasPersonalBest = false
+
+	if game.Mode != nil {
+		personalBest, hasPersonalBest = BestRecord(game.Mode)
+	}
+}
+
+// ClearNames names the line clears by number of lines cleared at once
+var ClearNames = [engine.MaxPieceSize + 1]string{"", "SINGLE", "DOUBLE", "TRIPLE", "TETRIS", "PENTRIS"}
+
+// ScoreAttack reports whether a mode is played for the score rather than against the clock
+func ScoreAttack(m engine.Mode) bool {
+	switch m.(type) {
+	case engine.Marathon, engine.Ultra:
+		return true
+	}
+	return false
+}
+
+// ShowResults reports whether the end of a game has results to show, races only having some when finished
+func ShowResults(g *engine.Game) bool {
+	switch g.Mode.(type) {
+	case nil:
+		return false
+	case engine.Marathon, engine.Ultra, *engine.Puzzle:
+		return true
+	}
+	return g.Finished
+}
+
+// BestRecord returns the best result of a mode, the highest score for score attacks and the fastest one for races
+func BestRecord(m engine.Mode) (highscore.Record, bool) {
+	if ScoreAttack(m) {
+		return records.Highest(m.Name())
+	}
+	return records.Fastest(m.Name())
+}
+
+// SaveRecord adds the result of the game to the high score file, once. Races
+// only count when they are finished, score attacks also when topped out.
+func SaveRecord() {
+	if recorded || autoplay || game.Mode == nil || PlayingEditor() || (!game.Finished && !ScoreAttack(game.Mode)) {
+		return
+	}
+	recorded = true
+
+	err := records.Add(highscore.Record{
+		Mode:   game.Mode.Name(),
+		Frames: game.Frame,
+		Score:  game.Score,
+		Lines:  game.Lines,
+		Splits: append([]int(nil), game.Splits[:game.SplitCount]...),
+		Date:   time.Now(),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// DrawModeStatus draws the time and goal of the game mode, if any
+func DrawModeStatus(g *engine.Game, offset rl.Vector2) {
+	if g.Mode == nil {
+		return
+	}
+
+	rl.DrawText(g.Mode.Name(), int32(offset.X), int32(offset.Y), layout.Text(10), rl.Gray)
+
+	switch m := g.Mode.(type) {
+	case engine.Ultra:
+		rl.DrawText("TIME LEFT:  "+FormatFrames(max(m.Frames-g.Frame, 0)), int32(offset.X), int32(offset.Y+layout.Size(20)), layout.Text(10), rl.Gray)
+	default:
+		rl.DrawText("TIME:  "+FormatFrames(g.Frame), int32(offset.X), int32(offset.Y+layout.Size(20)), layout.Text(10), rl.Gray)
+	}
+
+	switch m := g.Mode.(type) {
+	case engine.Marathon:
+		rl.DrawText(fmt.Sprintf("LEVEL:  %02d", g.Level), int32(offset.X), int32(offset.Y+layout.Size(35)), layout.Text(10), rl.Gray)
+		if m.Lines > 0 {
+			rl.DrawText(fmt.Sprintf("LINES LEFT:  %03d", max(m.Lines-g.Lines, 0)), int32(offset.X), int32(offset.Y+layout.Size(50)), layout.Text(10), rl.Gray)
+		}
+	case engine.Sprint:
+		rl.DrawText(fmt.Sprintf("LINES LEFT:  %02d", max(m.Lines-g.Lines, 0)), int32(offset.X), int32(offset.Y+layout.Size(35)), layout.Text(10), rl.Gray)
+		DrawSplits(g, rl.Vector2{X: offset.X, Y: offset.Y + layout.Size(60)})
+	case engine.CheeseRace:
+		rl.DrawText(fmt.Sprintf("GARBAGE LEFT:  %02d", m.Remaining(g)), int32(offset.X), int32(offset.Y+layout.Size(35)), layout.Text(10), rl.Gray)
+	case *engine.Puzzle:
+		if len(m.Pieces) > 0 {
+			rl.DrawText(fmt.Sprintf("PIECES LEFT:  %02d", PiecesLeft(g)), int32(offset.X), int32(offset.Y+layout.Size(35)), layout.Text(10), rl.Gray)
+		}
+		rl.DrawText(FormatGoal(m.Goal), int32(offset.X), int32(offset.Y+layout.Size(50)), layout.Text(10), rl.Gray)
+		DrawTarget(m.Goal.Target, rl.Vector2{X: offset.X, Y: offset.Y + layout.Size(90)})
+	}
+
+	if hasPersonalBest {
+		best := "BEST:  " + FormatFrames(personalBest.Frames)
+		if ScoreAttack(g.Mode) {
+			best = fmt.Sprintf("BEST:  %08d", personalBest.Score)
+		}
+		rl.DrawText(best, int32(offset.X), int32(offset.Y+layout.Size(65+15*float32(g.SplitCount))), layout.Text(10), rl.Gray)
+	}
+}
+
+// DrawCountdown draws the seconds left before the game starts
+func DrawCountdown(g *engine.Game) {
+	if g.Countdown <= 0 {
+		return
+	}
+
+	text := fmt.Sprint((g.Countdown + engine.FrameRate - 1) / engine.FrameRate)
+	// Centred over the grid
+	x := layout.Grid.X + engine.GridHorizontalSize*layout.Square/2
+	rl.DrawText(text, int32(x)-rl.MeasureText(text, layout.Text(80))/2, int32(layout.Center.Y-layout.Size(40)), layout.Text(80), rl.Maroon)
+}
+
+// DrawSplits lists the split times of a game, ahead of the personal best in green and behind it in red
+func DrawSplits(g *engine.Game, offset rl.Vector2) {
+	for n, frames := range g.Splits[:g.SplitCount] {
+		text := fmt.Sprintf("%3d  %s", (n+1)*engine.SprintSplitLines, FormatFrames(frames))
+		rl.DrawText(text, int32(offset.X), int32(offset.Y), layout.Text(10), rl.Gray)
+
+		if hasPersonalBest && n < len(personalBest.Splits) {
+			delta := frames - personalBest.Splits[n]
+			color := rl.Red
+			if delta <= 0 {
+				color = rl.DarkGreen
+			}
+			rl.DrawText(FormatDelta(delta), int32(offset.X)+rl.MeasureText(text, layout.Text(10))+int32(layout.Size(10)), int32(offset.Y), layout.Text(10), color)
+		}
+
+		offset.Y += layout.Size(15)
+	}
+}
+
+// DrawResults shows how a finished game compares with the personal best
+func DrawResults(g *engine.Game) {
+	if _, ok := g.Mode.(*engine.Puzzle); ok {
+		DrawPuzzleResults(g)
+		return
+	}
+
+	if ScoreAttack(g.Mode) {
+		DrawScoreResults(g)
+		return
+	}
+
+	result := "FINISHED IN " + FormatFrames(g.Frame)
+	DrawCentered(result, layout.Center.Y-layout.Size(185), 40, rl.Maroon)
+
+	best := "NEW PERSONAL BEST!"
+	if hasPersonalBest && personalBest.Frames <= g.Frame {
+		best = fmt.Sprintf("PERSONAL BEST: %s (%s)", FormatFrames(personalBest.Frames), FormatDelta(g.Frame-personalBest.Frames))
+	}
+	DrawCentered(best, layout.Center.Y-layout.Size(130), 20, rl.Gray)
+
+	if g.SplitCount > 0 {
+		DrawSplits(g, rl.Vector2{X: layout.Center.X - layout.Size(60), Y: layout.Center.Y - layout.Size(85)})
+	}
+}
+
+// DrawScoreResults shows the score of a score attack and the statistics of the game
+func DrawScoreResults(g *engine.Game) {
+	result := fmt.Sprintf("SCORE %d", g.Score)
+	DrawCentered(result, layout.Center.Y-layout.Size(185), 40, rl.Maroon)
+
+	best := "NEW PERSONAL BEST!"
+	if hasPersonalBest && personalBest.Score >= g.Score {
+		best = fmt.Sprintf("PERSONAL BEST: %d", personalBest.Score)
+	}
+	DrawCentered(best, layout.Center.Y-layout.Size(130), 20, rl.Gray)
+
+	offset := rl.Vector2{X: layout.Center.X - layout.Size(60), Y: layout.Center.Y - layout.Size(85)}
+	for lines := 1; lines <= engine.MaxPieceSize; lines++ {
+		// Only piece sets with pieces five squares tall can clear five lines
+		if lines == engine.MaxPieceSize && g.Clears[lines] == 0 {
+			break
+		}
+
+		rl.DrawText(fmt.Sprintf("%-8s %4d", ClearNames[lines]+":", g.Clears[lines]), int32(offset.X), int32(offset.Y), layout.Text(10), rl.Gray)
+		offset.Y += layout.Size(15)
+	}
+
+	offset.Y += layout.Size(5)
+	stats := []string{fmt.Sprintf("%-8s %4d", "LINES:", g.Lines), fmt.Sprintf("%-8s %4d", "PIECES:", g.Pieces)}
+	if g.Level > 0 {
+		stats = append(stats, fmt.Sprintf("%-8s %4d", "LEVEL:", g.Level))
+	}
+	stats = append(stats, "TIME:    "+FormatFrames(g.Frame))
+
+	for _, text := range stats {
+		rl.DrawText(text, int32(offset.X), int32(offset.Y), layout.Text(10), rl.Gray)
+		offset.Y += layout.Size(15)
+	}
+}
+
+// FormatFrames formats a number of frames at engine.FrameRate per second as minutes, seconds and hundredths
+func FormatFrames(frames int) string {
+	hundredths := frames * 100 / engine.FrameRate
+	return fmt.Sprintf("%02d:%02d.%02d", hundredths/6000, hundredths/100%60, hundredths%100)
+}
+
+// FormatDelta formats the difference between two times in frames, with its sign
+func FormatDelta(frames int) string {
+	if frames < 0 {
+		return "-" + FormatFrames(-frames)
+	}
+	return "+" + FormatFrames(frames)
+}
@@ -0,0 +1,242 @@
+// Package netplay plays a versus match between two machines in lockstep.
+// Both peers run the same deterministic match from the same seed and only
+// trade the inputs of every frame, so garbage and pieces stay in sync on
+// their own. Inputs are played a few frames after they are read to hide
+// the network latency, and every frame carries a checksum of the match so a
+// desync is caught as soon as it happens.
+package netplay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"time"
+
+	"tetris/main/engine"
+	"tetris/main/versus"
+)
+
+// Version of the protocol, checked during the handshake
+const Version = 2
+
+// maxDelay bounds the input delay, and so how many checksums are kept
+const maxDelay = 120
+
+// ErrDesync is returned when the two matches stop being identical
+var ErrDesync = errors.New("netplay: matches out of sync")
+
+// Config of a networked match, chosen by the host
+type Config struct {
+	Set          *engine.PieceSet
+	Seed         int64
+	GarbageDelay int
+	Timing       engine.Timing
+	InputDelay   int           // Frames between reading an input and playing it
+	Timeout      time.Duration // Time to wait for the peer before giving up, 0 to wait forever
+}
+
+// handshake is sent by the host when the guest connects
+type handshake struct {
+	Version      int              `json:"version"`
+	Seed         int64            `json:"seed"`
+	GarbageDelay int              `json:"garbageDelay"`
+	Timing       engine.Timing    `json:"timing"`
+	InputDelay   int              `json:"inputDelay"`
+	Set          *engine.PieceSet `json:"set"`
+}
+
+// Session is one end of a networked match
+type Session struct {
+	Match *versus.Match
+	Local int // Index of the player on this machine, 0 for the host
+
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	local     []engine.Input // Inputs read here and not played yet
+	remote    []engine.Input // Inputs of the peer not played yet
+	frame     int            // Frames played so far
+	received  int            // Inputs received from the peer
+	checksums [maxDelay + 1]uint32
+}
+
+// Host waits for a guest on the address and starts the match, the host being player one
+func Host(address string, config Config) (*Session, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	return Accept(l, config)
+}
+
+// Accept waits for a guest on a listener and starts the match as its host
+func Accept(l net.Listener, config Config) (*Session, error) {
+	if config.InputDelay < 1 || config.InputDelay > maxDelay {
+		return nil, fmt.Errorf("netplay: input delay must be between 1 and %d", maxDelay)
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.NewEncoder(conn).Encode(handshake{
+		Version:      Version,
+		Seed:         config.Seed,
+		GarbageDelay: config.GarbageDelay,
+		Timing:       config.Timing,
+		InputDelay:   config.InputDelay,
+		Set:          config.Set,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return newSession(conn, bufio.NewReader(conn), 0, config), nil
+}
+
+// Join connects to a host and starts the match as player two
+func Join(address string, timeout time.Duration) (*Session, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The host may take as long to send the match as to answer
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var h handshake
+	if err := json.Unmarshal(line, &h); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if h.Version != Version {
+		conn.Close()
+		return nil, fmt.Errorf("netplay: host speaks protocol version %d, want %d", h.Version, Version)
+	}
+	if h.InputDelay < 1 || h.InputDelay > maxDelay {
+		conn.Close()
+		return nil, fmt.Errorf("netplay: bad input delay %d", h.InputDelay)
+	}
+
+	config := Config{
+		Set:          h.Set,
+		Seed:         h.Seed,
+		GarbageDelay: h.GarbageDelay,
+		Timing:       h.Timing,
+		InputDelay:   h.InputDelay,
+		Timeout:      timeout,
+	}
+
+	return newSession(conn, reader, 1, config), nil
+}
+
+func newSession(conn net.Conn, reader *bufio.Reader, local int, config Config) *Session {
+	s := &Session{
+		Match: versus.NewMatch(versus.Config{
+			Set:          config.Set,
+			Seed:         config.Seed,
+			GarbageDelay: config.GarbageDelay,
+			Timing:       config.Timing,
+		}),
+		Local:   local,
+		conn:    conn,
+		reader:  reader,
+		timeout: config.Timeout,
+	}
+
+	// Nobody presses anything during the first frames
+	s.local = make([]engine.Input, config.InputDelay)
+	s.remote = make([]engine.Input, config.InputDelay)
+
+	return s
+}
+
+// Close ends the session
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+// Step sends the input read this frame and plays the next frame of the
+// match, waiting for the peer's input if it has not arrived yet
+func (s *Session) Step(input engine.Input) error {
+	// Every packet carries the checksum of the frame it was sent on
+	s.checksums[s.frame%len(s.checksums)] = checksum(s.Match)
+
+	var packet [5]byte
+	packet[0] = byte(input)
+	binary.BigEndian.PutUint32(packet[1:], s.checksums[s.frame%len(s.checksums)])
+
+	if s.timeout > 0 {
+		s.conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+	if _, err := s.conn.Write(packet[:]); err != nil {
+		return err
+	}
+	s.local = append(s.local, input)
+
+	if len(s.remote) == 0 {
+		if _, err := io.ReadFull(s.reader, packet[:]); err != nil {
+			return err
+		}
+
+		// The n-th packet of the peer was sent on its n-th frame, a few frames back
+		if binary.BigEndian.Uint32(packet[1:]) != s.checksums[s.received%len(s.checksums)] {
+			return ErrDesync
+		}
+
+		s.remote = append(s.remote, engine.Input(packet[0]))
+		s.received++
+	}
+
+	var inputs [2]engine.Input
+	inputs[s.Local] = s.local[0]
+	inputs[1-s.Local] = s.remote[0]
+	s.local = s.local[1:]
+	s.remote = s.remote[1:]
+
+	s.Match.Step(inputs)
+	s.frame++
+
+	return nil
+}
+
+// checksum hashes everything that both ends must agree on
+func checksum(m *versus.Match) uint32 {
+	h := fnv.New32a()
+
+	for _, g := range m.Players {
+		var buf [8]byte
+		board := g.Board()
+		for i := range board {
+			for j := range board[i] {
+				h.Write([]byte{byte(board[i][j])})
+			}
+		}
+		binary.BigEndian.PutUint64(buf[:], uint64(g.Frame))
+		h.Write(buf[:])
+		binary.BigEndian.PutUint64(buf[:], uint64(g.Lines<<32|g.PendingGarbageRows()))
+		h.Write(buf[:])
+	}
+
+	return h.Sum32()
+}
@@ -0,0 +1,103 @@
+package netplay
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+func TestLockstep(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	config := Config{Set: engine.DefaultPieceSet(), Seed: 3, GarbageDelay: 30, Timing: engine.GuidelineTiming, InputDelay: 4, Timeout: 5 * time.Second}
+
+	hosted := make(chan *Session)
+	go func() {
+		s, err := Accept(l, config)
+		if err != nil {
+			t.Error(err)
+		}
+		hosted <- s
+	}()
+
+	guest, err := Join(l.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := <-hosted
+	if host == nil {
+		t.FailNow()
+	}
+	defer host.Close()
+	defer guest.Close()
+
+	if timing := guest.Match.Players[0].Timing; timing != engine.GuidelineTiming {
+		t.Fatalf("guest plays %s timing, want the host's %s", timing.Name, engine.GuidelineTiming.Name)
+	}
+
+	// Each machine only drives its own player, with different weights so the games differ
+	run := func(s *Session, player *ai.Player, done chan error) {
+		for i := 0; i < 3000; i++ {
+			if err := s.Step(player.Next(s.Match.Players[s.Local])); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}
+
+	strong := ai.NewPlayer(ai.DefaultWeights())
+	weak := ai.NewPlayer(ai.Weights{AggregateHeight: -0.1, Holes: -0.1, Lines: 1})
+
+	done := make(chan error, 2)
+	go run(host, strong, done)
+	go run(guest, weak, done)
+
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := range host.Match.Players {
+		h, g := host.Match.Players[n], guest.Match.Players[n]
+		if h.Grid != g.Grid || h.Lines != g.Lines || h.Frame != g.Frame {
+			t.Errorf("player %d differs between the two machines", n+1)
+		}
+	}
+	if host.Match.Players[0].Lines == 0 {
+		t.Errorf("host player cleared no lines")
+	}
+}
+
+func TestJoinTimeout(t *testing.T) {
+	// A host that takes the connection but never sends the match
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	start := time.Now()
+	if _, err := Join(l.Addr().String(), 100*time.Millisecond); err == nil {
+		t.Fatal("joined a host that sent nothing")
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("gave up after %v, want about the 100ms timeout", waited)
+	}
+}
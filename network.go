@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/netplay"
+)
+
+// Networked match state
+var (
+	session      *netplay.Session
+	networkError error
+)
+
+// ConnectNetplay hosts or joins a networked match before the window opens
+func ConnectNetplay(host, join string, inputDelay int) error {
+	var err error
+
+	if host != "" {
+		fmt.Fprintf(os.Stderr, "waiting for an opponent on %s\n", host)
+		session, err = netplay.Host(host, netplay.Config{
+			Set:          pieceSet,
+			Seed:         time.Now().UnixNano(),
+			GarbageDelay: garbageDelay,
+			Timing:       defaultTiming,
+			InputDelay:   inputDelay,
+			Timeout:      10 * time.Second,
+		})
+	} else {
+		session, err = netplay.Join(join, 10*time.Second)
+	}
+	if err != nil {
+		return err
+	}
+
+	screen = NetplayScreen
+	match = session.Match
+
+	return nil
+}
+
+// UpdateNetplay plays the next frame of the networked match with the local player's input
+func UpdateNetplay() {
+	if networkError != nil || match.Over() {
+		if rl.IsKeyPressed(rl.KeyEnter) {
+			session.Close()
+			InitTitle()
+		}
+		return
+	}
+
+	playerInputs[0].Hold(ReadInput(SinglePlayerKeys))
+
+	for i := 0; i < steps && !match.Over(); i++ {
+		if err := session.Step(playerInputs[0].Take()); err != nil {
+			networkError = err
+			session.Close()
+			return
+		}
+		ObserveVersus()
+	}
+	UpdateVersusEffects()
+}
+
+// DrawNetplay draws the networked match like a local one
+func DrawNetplay() {
+	DrawVersus()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+)
+
+var (
+	puzzles         []*engine.Puzzle // Puzzles of the puzzle folder
+	puzzleSelection int              // Index of the selected puzzle
+)
+
+// InitPuzzles shows the puzzle select screen
+func InitPuzzles() {
+	screen = PuzzleScreen
+}
+
+// UpdatePuzzles moves through the puzzles, starting the chosen one
+func UpdatePuzzles() {
+	if rl.IsKeyPressed(rl.KeyBackspace) || (len(puzzles) == 0 && rl.IsKeyPressed(rl.KeyEnter)) {
+		InitTitle()
+		return
+	}
+
+	if len(puzzles) == 0 {
+		return
+	}
+
+	if rl.IsKeyPressed(rl.KeyDown) {
+		puzzleSelection = (puzzleSelection + 1) % len(puzzles)
+	}
+	if rl.IsKeyPressed(rl.KeyUp) {
+		puzzleSelection = (puzzleSelection + len(puzzles) - 1) % len(puzzles)
+	}
+
+	if rl.IsKeyPressed(rl.KeyEnter) {
+		StartMode(puzzles[puzzleSelection])
+	}
+}
+
+// DrawPuzzles draws the puzzle select screen
+func DrawPuzzles() {
+	rl.BeginDrawing()
+
+	rl.ClearBackground(rl.RayWhite)
+
+	DrawCentered("PUZZLES", layout.Center.Y-layout.Size(195), 40, rl.Black)
+
+	if len(puzzles) == 0 {
+		DrawCentered("NO PUZZLES FOUND", layout.Center.Y-layout.Size(10), 20, rl.Gray)
+	}
+
+	for n, p := range puzzles {
+		color := rl.Gray
+		text := fmt.Sprintf("%d. %s", n+1, strings.ToUpper(p.Title))
+		if n == puzzleSelection {
+			color = rl.Maroon
+			text = "> " + text + " <"
+		}
+		rl.DrawText(text, int32(layout.Center.X-layout.Size(300)), int32(layout.Center.Y+layout.Size(25*float32(n)-125)), layout.Text(20), color)
+	}
+
+	if len(puzzles) > 0 {
+		p := puzzles[puzzleSelection]
+		x := int32(layout.Center.X)
+		rl.DrawText(p.Description, x, int32(layout.Center.Y-layout.Size(125)), layout.Text(10), rl.DarkGray)
+		rl.DrawText(FormatGoal(p.Goal), x, int32(layout.Center.Y-layout.Size(105)), layout.Text(10), rl.Gray)
+		rl.DrawText(fmt.Sprintf("PIECES: %s", strings.Join(p.Pieces, " ")), x, int32(layout.Center.Y-layout.Size(90)), layout.Text(10), rl.Gray)
+	}
+
+	DrawCentered("[UP]/[DOWN] TO CHOOSE, [ENTER] TO PLAY, [BACKSPACE] FOR THE TITLE", layout.Center.Y+layout.Size(195), 10, rl.Gray)
+
+	rl.EndDrawing()
+}
+
+// FormatGoal describes the goal of a puzzle
+func FormatGoal(goal engine.PuzzleGoal) string {
+	var parts []string
+
+	if goal.Lines > 0 {
+		parts = append(parts, fmt.Sprintf("CLEAR %d LINES", goal.Lines))
+	}
+	if goal.Clear > 0 {
+		parts = append(parts, fmt.Sprintf("CLEAR %d LINES AT ONCE", goal.Clear))
+	}
+	if goal.PerfectClear {
+		parts = append(parts, "EMPTY THE GRID")
+	}
+	if len(goal.Target) > 0 {
+		parts = append(parts, "BUILD THE TARGET SHAPE")
+	}
+	if len(parts) == 0 {
+		return "NO GOAL, PLAY UNTIL THE STACK TOPS OUT"
+	}
+
+	return "GOAL: " + strings.Join(parts, ", ")
+}
+
+// DrawTarget draws the rows a puzzle asks to build in small squares, leaving out the ones that can be anything
+func DrawTarget(rows []string, offset rl.Vector2) {
+	size := layout.Square / 2
+
+	controller := offset.X
+
+	for _, row := range rows {
+		for _, square := range row {
+			switch square {
+			case '#':
+				rl.DrawRectangle(int32(offset.X), int32(offset.Y), int32(size), int32(size), rl.Black)
+			case '.':
+				rl.DrawRectangleLines(int32(offset.X), int32(offset.Y), int32(size), int32(size), rl.LightGray)
+			}
+
+			offset.X += size
+		}
+
+		offset.X = controller
+		offset.Y += size
+	}
+}
+
+// PiecesLeft counts the pieces of a puzzle still to be played, the active one excluded
+func PiecesLeft(g *engine.Game) int {
+	left := g.QueueLength
+	if g.IncomingType >= 0 {
+		left++
+	}
+	if g.HoldType >= 0 {
+		left++
+	}
+	return left
+}
+
+// DrawPuzzleResults tells whether the puzzle was solved
+func DrawPuzzleResults(g *engine.Game) {
+	p := g.Mode.(*engine.Puzzle)
+
+	result := "PUZZLE SOLVED!"
+	if p.Goal.Empty() {
+		result = "GAME OVER"
+	} else if !g.Finished {
+		result = "PUZZLE FAILED"
+	}
+	DrawCentered(result, layout.Center.Y-layout.Size(185), 40, rl.Maroon)
+
+	title := strings.ToUpper(p.Title)
+	DrawCentered(title, layout.Center.Y-layout.Size(130), 20, rl.Gray)
+
+	back := "PRESS [BACKSPACE] FOR THE PUZZLE LIST"
+	if PlayingEditor() {
+		back = "PRESS [BACKSPACE] FOR THE EDITOR"
+	}
+	DrawCentered(back, layout.Center.Y+layout.Size(145), 20, rl.Gray)
+}
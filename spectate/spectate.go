@@ -0,0 +1,221 @@
+// Package spectate streams running games to web browsers. Every change of a
+// game (spawned, moved, locked pieces, line clears, score) is sent as a
+// server-sent event, and the server also hosts a small page drawing the
+// boards, so anyone on the machine can watch a game from a browser.
+package spectate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"tetris/main/engine"
+)
+
+//go:embed index.html
+var page []byte
+
+// Board is the state of one game as sent to spectators
+type Board struct {
+	Event    string   `json:"event"` // What changed: spawn, move, lock, clear, lines, gameover, start, or leave when the game is gone
+	Player   int      `json:"player"`
+	Frame    int      `json:"frame"`
+	Lines    int      `json:"lines"`
+	Score    int      `json:"score"`
+	Pieces   int      `json:"pieces"`
+	Spawned  int      `json:"spawned"`
+	GameOver bool     `json:"gameOver"`
+	Rows     []string `json:"rows"` // Board notation: '.' empty, '#' full, '@' moving, '~' fading
+	Incoming string   `json:"incoming"`
+	Hold     string   `json:"hold"`
+}
+
+// Server keeps the latest state of every game and pushes changes to the connected browsers
+type Server struct {
+	mutex   sync.Mutex
+	clients map[chan []byte]struct{}
+	last    map[int]*Board
+}
+
+// NewServer returns a server with no game yet
+func NewServer() *Server {
+	return &Server{
+		clients: make(map[chan []byte]struct{}),
+		last:    make(map[int]*Board),
+	}
+}
+
+// ListenAndServe serves the page and the event stream on the address in the background
+func (s *Server) ListenAndServe(address string) (net.Addr, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	go http.Serve(l, s)
+
+	return l.Addr(), nil
+}
+
+// ServeHTTP serves the viewer page on / and the event stream on /events
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	case "/events":
+		s.stream(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// stream sends the current boards, then every change until the browser goes away
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	events := make(chan []byte, 64)
+
+	s.mutex.Lock()
+	for _, board := range s.last {
+		data, _ := json.Marshal(board)
+		events <- data
+	}
+	s.clients[events] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, events)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case data := <-events:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Publish sends the state of a player's game to the spectators if it
+// changed since the last call. It is called after every step of the game so
+// that each change gets its own event.
+func (s *Server) Publish(player int, g *engine.Game) {
+	board := NewBoard(player, g)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	last := s.last[player]
+	board.Event = change(last, board)
+	if board.Event == "" {
+		return
+	}
+	s.last[player] = board
+
+	s.broadcast(board)
+}
+
+// Remove takes a player's game off the spectators' screens, such as the
+// second board once a versus match gives way to a single player game
+func (s *Server) Remove(player int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.last[player]; !ok {
+		return
+	}
+	delete(s.last, player)
+
+	s.broadcast(&Board{Event: "leave", Player: player})
+}
+
+// broadcast sends a board to every connected browser, the mutex held
+func (s *Server) broadcast(board *Board) {
+	data, err := json.Marshal(board)
+	if err != nil {
+		return
+	}
+
+	for client := range s.clients {
+		// A browser that cannot keep up misses updates instead of holding the game back
+		select {
+		case client <- data:
+		default:
+		}
+	}
+}
+
+// NewBoard takes the state of a game
+func NewBoard(player int, g *engine.Game) *Board {
+	board := &Board{
+		Player:   player,
+		Frame:    g.Frame,
+		Lines:    g.Lines,
+		Score:    g.Score,
+		Pieces:   g.Pieces,
+		Spawned:  g.Spawned,
+		GameOver: g.GameOver,
+	}
+	if g.IncomingType >= 0 {
+		board.Incoming = g.Set.Pieces[g.IncomingType].Name
+	}
+	if g.HoldType >= 0 {
+		board.Hold = g.Set.Pieces[g.HoldType].Name
+	}
+
+	grid := g.Board()
+	board.Rows = engine.FormatRows(&grid)
+
+	return board
+}
+
+// change names what happened between two states of a game, "" when nothing visible did
+func change(last, board *Board) string {
+	switch {
+	case last == nil || board.Frame < last.Frame:
+		return "start"
+	case board.GameOver && !last.GameOver:
+		return "gameover"
+	case board.Lines != last.Lines || board.Score != last.Score:
+		return "lines"
+	case fading(board) && !fading(last):
+		return "clear"
+	case board.Pieces != last.Pieces:
+		return "lock"
+	case board.Spawned != last.Spawned:
+		return "spawn"
+	case !slices.Equal(board.Rows, last.Rows):
+		return "move"
+	}
+
+	return ""
+}
+
+// fading reports whether lines of the board are being cleared
+func fading(board *Board) bool {
+	for _, row := range board.Rows {
+		if strings.ContainsRune(row, engine.FadingChar) {
+			return true
+		}
+	}
+	return false
+}
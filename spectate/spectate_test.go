@@ -0,0 +1,155 @@
+package spectate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tetris/main/engine"
+)
+
+// readEvent reads the next server-sent event of a stream
+func readEvent(t *testing.T, reader *bufio.Reader) *Board {
+	t.Helper()
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := strings.CutPrefix(line, "data: ")
+	if !ok {
+		t.Fatalf("event line %q does not start with data:", line)
+	}
+	if blank, err := reader.ReadString('\n'); err != nil || blank != "\n" {
+		t.Fatalf("event not ended by a blank line but %q", blank)
+	}
+
+	var board Board
+	if err := json.Unmarshal([]byte(data), &board); err != nil {
+		t.Fatal(err)
+	}
+	return &board
+}
+
+func TestPage(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(body), "EventSource") {
+		t.Errorf("page served as %q without the event stream", response.Header.Get("Content-Type"))
+	}
+
+	response, err = http.Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("missing page answered %d", response.StatusCode)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	s := NewServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	g := engine.NewGame(engine.DefaultPieceSet(), 1)
+	s.Publish(0, g)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("events served as %q", response.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(response.Body)
+
+	// A browser joining late first gets the boards as they are
+	if board := readEvent(t, reader); board.Event != "start" || board.Player != 0 || len(board.Rows) != engine.GridVerticalSize-1 {
+		t.Fatalf("first event %+v, want the start of player 1", board)
+	}
+
+	// Every step that changes the game is its own event: a lock and the
+	// spawn on the next step are not merged
+	var events []string
+	for g.Pieces < 1 {
+		g.Step(engine.InputDown)
+		s.Publish(0, g)
+	}
+	g.Step(0)
+	s.Publish(0, g)
+	for {
+		board := readEvent(t, reader)
+		events = append(events, board.Event)
+		if board.Frame == g.Frame {
+			break
+		}
+	}
+	if got := strings.Join(events, " "); !strings.Contains(got, "lock spawn") {
+		t.Errorf("events %s, want a lock followed by a spawn", got)
+	}
+
+	// Publishing an unchanged game sends nothing, the second board comes and goes
+	s.Publish(0, g)
+	s.Remove(1)
+	s.Publish(1, engine.NewGame(engine.DefaultPieceSet(), 2))
+	s.Remove(1)
+
+	if board := readEvent(t, reader); board.Event != "start" || board.Player != 1 {
+		t.Errorf("got %+v, want the start of player 2", board)
+	}
+	if board := readEvent(t, reader); board.Event != "leave" || board.Player != 1 {
+		t.Errorf("got %+v, want player 2 to leave", board)
+	}
+}
+
+func TestChange(t *testing.T) {
+	last := &Board{Frame: 10, Lines: 2, Score: 300, Pieces: 5, Spawned: 6, Rows: []string{"..@.......", "##.#######"}}
+
+	tests := []struct {
+		name  string
+		apply func(b *Board)
+		want  string
+	}{
+		{"nothing", func(b *Board) { b.Frame++ }, ""},
+		{"new game", func(b *Board) { b.Frame = 1 }, "start"},
+		{"game over", func(b *Board) { b.GameOver = true }, "gameover"},
+		{"lines", func(b *Board) { b.Lines++ }, "lines"},
+		{"clear", func(b *Board) { b.Rows = []string{"..........", "~~~~~~~~~~"} }, "clear"},
+		{"lock", func(b *Board) { b.Pieces++ }, "lock"},
+		{"spawn", func(b *Board) { b.Spawned++ }, "spawn"},
+		{"move", func(b *Board) { b.Rows = []string{"...@......", "##.#######"} }, "move"},
+	}
+
+	for _, test := range tests {
+		board := *last
+		test.apply(&board)
+		if got := change(last, &board); got != test.want {
+			t.Errorf("%s: change is %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	if got := change(nil, last); got != "start" {
+		t.Errorf("first board is %q, want start", got)
+	}
+}
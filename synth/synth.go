@@ -0,0 +1,223 @@
+// Package synth makes the sound effects and the music of the game from
+// simple waveforms, as 16 bit mono samples or WAV data ready for an audio
+// device, so the game needs no sound files.
+package synth
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// SampleRate is the number of samples in a second of sound
+const SampleRate = 22050
+
+// Sound is one of the sound effects of the game
+type Sound int
+
+// Sound effects, the clears in order of lines cleared at once
+const (
+	Move Sound = iota
+	Rotate
+	Lock
+	Single
+	Double
+	Triple
+	Tetris
+	Pentris
+	LevelUp
+	GameOver
+	SoundCount
+)
+
+// Clear returns the sound of clearing a number of lines at once
+func Clear(lines int) Sound {
+	return Single + Sound(min(max(lines, 1), int(Pentris-Single)+1)-1)
+}
+
+// Waveform is the shape of one period of a tone, given the phase from 0 to 1
+type Waveform func(phase float64) float64
+
+// Waveforms
+var (
+	Square   Waveform = func(p float64) float64 { return math.Copysign(1, 0.5-p) }
+	Triangle Waveform = func(p float64) float64 { return 4*math.Abs(p-0.5) - 1 }
+	Sine     Waveform = func(p float64) float64 { return math.Sin(2 * math.Pi * p) }
+)
+
+// Note is a tone of a frequency lasting a number of beats, a rest when the frequency is 0
+type Note struct {
+	Frequency float64
+	Beats     float64
+}
+
+// Pitch returns the frequency of a note given in semitones from A4 (440Hz)
+func Pitch(semitones int) float64 {
+	return 440 * math.Pow(2, float64(semitones)/12)
+}
+
+// Notes of the scale around A4, in semitones from it
+const (
+	A3 = -12 + iota
+	As3
+	B3
+	C4
+	Cs4
+	D4
+	Ds4
+	E4
+	F4
+	Fs4
+	G4
+	Gs4
+	A4
+	As4
+	B4
+	C5
+	Cs5
+	D5
+	Ds5
+	E5
+	F5
+	Fs5
+	G5
+	Gs5
+	A5
+)
+
+// Play renders notes at a tempo, each one fading out over its length
+func Play(notes []Note, bpm float64, wave Waveform, volume float64) []int16 {
+	var samples []int16
+
+	for _, note := range notes {
+		length := int(note.Beats * 60 / bpm * SampleRate)
+		for n := 0; n < length; n++ {
+			value := 0.0
+			if note.Frequency > 0 {
+				t := float64(n) / SampleRate
+				phase := math.Mod(t*note.Frequency, 1)
+				// A quick attack and a decay to half volume, then a short release against clicks
+				envelope := 1 - 0.5*float64(n)/float64(length)
+				envelope *= min(1, float64(n)/40, float64(length-n)/200)
+				value = wave(phase) * envelope * volume
+			}
+			samples = append(samples, int16(value*math.MaxInt16))
+		}
+	}
+
+	return samples
+}
+
+// Mix adds sounds together, the result as long as the longest
+func Mix(sounds ...[]int16) []int16 {
+	var mixed []int16
+
+	for _, sound := range sounds {
+		for len(mixed) < len(sound) {
+			mixed = append(mixed, 0)
+		}
+		for n, sample := range sound {
+			mixed[n] = int16(max(math.MinInt16, min(math.MaxInt16, int(mixed[n])+int(sample))))
+		}
+	}
+
+	return mixed
+}
+
+// Sweep renders a tone gliding from one frequency to another over a duration in seconds
+func Sweep(from, to, seconds float64, wave Waveform, volume float64) []int16 {
+	length := int(seconds * SampleRate)
+	samples := make([]int16, length)
+
+	phase := 0.0
+	for n := range samples {
+		progress := float64(n) / float64(length)
+		phase = math.Mod(phase+(from+(to-from)*progress)/SampleRate, 1)
+		samples[n] = int16(wave(phase) * (1 - progress) * volume * math.MaxInt16)
+	}
+
+	return samples
+}
+
+// Samples returns the samples of a sound effect
+func Samples(sound Sound) []int16 {
+	switch sound {
+	case Move:
+		return Sweep(Pitch(E5), Pitch(C5), 0.03, Square, 0.15)
+	case Rotate:
+		return Sweep(Pitch(A4), Pitch(A5), 0.05, Triangle, 0.3)
+	case Lock:
+		return Sweep(Pitch(A3), Pitch(A3)/2, 0.08, Square, 0.3)
+	case LevelUp:
+		return Play([]Note{{Pitch(C5), 0.5}, {Pitch(E5), 0.5}, {Pitch(G5), 0.5}, {Pitch(C5 + 12), 1.5}}, 480, Square, 0.25)
+	case GameOver:
+		return Play([]Note{{Pitch(E4), 1}, {Pitch(Ds4), 1}, {Pitch(D4), 1}, {Pitch(Cs4), 3}}, 180, Triangle, 0.4)
+	}
+
+	// Clears play a rising arpeggio, one note more for every line
+	if sound >= Single && sound <= Pentris {
+		arpeggio := []int{C5, E5, G5, C5 + 12, E5 + 12}
+		var notes []Note
+		for _, semitones := range arpeggio[:sound-Single+1] {
+			notes = append(notes, Note{Pitch(semitones), 0.5})
+		}
+		notes[len(notes)-1].Beats = 1.5
+		return Play(notes, 600, Square, 0.25)
+	}
+
+	return nil
+}
+
+// korobeiniki is the melody of the music, the first part of the folk song
+var korobeiniki = []Note{
+	{Pitch(E5), 1}, {Pitch(B4), 0.5}, {Pitch(C5), 0.5}, {Pitch(D5), 1}, {Pitch(C5), 0.5}, {Pitch(B4), 0.5},
+	{Pitch(A4), 1}, {Pitch(A4), 0.5}, {Pitch(C5), 0.5}, {Pitch(E5), 1}, {Pitch(D5), 0.5}, {Pitch(C5), 0.5},
+	{Pitch(B4), 1.5}, {Pitch(C5), 0.5}, {Pitch(D5), 1}, {Pitch(E5), 1},
+	{Pitch(C5), 1}, {Pitch(A4), 1}, {Pitch(A4), 2},
+	{0, 0.5}, {Pitch(D5), 1}, {Pitch(F5), 0.5}, {Pitch(A5), 1}, {Pitch(G5), 0.5}, {Pitch(F5), 0.5},
+	{Pitch(E5), 1.5}, {Pitch(C5), 0.5}, {Pitch(E5), 1}, {Pitch(D5), 0.5}, {Pitch(C5), 0.5},
+	{Pitch(B4), 1}, {Pitch(B4), 0.5}, {Pitch(C5), 0.5}, {Pitch(D5), 1}, {Pitch(E5), 1},
+	{Pitch(C5), 1}, {Pitch(A4), 1}, {Pitch(A4), 2},
+}
+
+// bass follows the chords of the melody, one root a bar of four beats
+var bass = []int{E4 - 24, A3 - 12, Gs4 - 24, A3 - 12, D4 - 24, C4 - 24, E4 - 24, A3 - 12}
+
+// MusicTempo is the tempo of the music in beats per minute, at normal speed
+const MusicTempo = 150
+
+// Music returns one loop of the music
+func Music() []int16 {
+	var line []Note
+	for _, root := range bass {
+		for i := 0; i < 4; i++ {
+			line = append(line, Note{Pitch(root), 0.5}, Note{Pitch(root + 12), 0.5})
+		}
+	}
+
+	return Mix(Play(korobeiniki, MusicTempo, Square, 0.18), Play(line, MusicTempo, Triangle, 0.25))
+}
+
+// WAV wraps samples in a WAV file
+func WAV(samples []int16) []byte {
+	const headerSize = 44
+	data := make([]byte, headerSize+2*len(samples))
+
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)           // Size of the format chunk
+	binary.LittleEndian.PutUint16(data[20:], 1)            // PCM
+	binary.LittleEndian.PutUint16(data[22:], 1)            // Mono
+	binary.LittleEndian.PutUint32(data[24:], SampleRate)   // Samples per second
+	binary.LittleEndian.PutUint32(data[28:], SampleRate*2) // Bytes per second
+	binary.LittleEndian.PutUint16(data[32:], 2)            // Bytes per sample
+	binary.LittleEndian.PutUint16(data[34:], 16)           // Bits per sample
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*len(samples)))
+
+	for n, sample := range samples {
+		binary.LittleEndian.PutUint16(data[headerSize+2*n:], uint16(sample))
+	}
+
+	return data
+}
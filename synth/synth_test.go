@@ -0,0 +1,81 @@
+package synth
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestClear(t *testing.T) {
+	for lines, want := range map[int]Sound{0: Single, 1: Single, 2: Double, 3: Triple, 4: Tetris, 5: Pentris, 8: Pentris} {
+		if got := Clear(lines); got != want {
+			t.Errorf("clearing %d lines plays %d, want %d", lines, got, want)
+		}
+	}
+}
+
+func TestSamples(t *testing.T) {
+	for sound := Move; sound < SoundCount; sound++ {
+		samples := Samples(sound)
+		if len(samples) == 0 {
+			t.Errorf("sound %d is silent", sound)
+			continue
+		}
+
+		loudest := 0
+		for _, sample := range samples {
+			loudest = max(loudest, int(sample), -int(sample))
+		}
+		if loudest < 1000 {
+			t.Errorf("sound %d peaks at %d, too quiet to hear", sound, loudest)
+		}
+	}
+
+	// Bigger clears last longer
+	for sound := Double; sound <= Pentris; sound++ {
+		if len(Samples(sound)) <= len(Samples(sound-1)) {
+			t.Errorf("sound %d is not longer than sound %d", sound, sound-1)
+		}
+	}
+}
+
+func TestMusic(t *testing.T) {
+	// Eight bars of four beats
+	want := int(32 * 60.0 / MusicTempo * SampleRate)
+	if got := len(Music()); got < want-len(korobeiniki) || got > want {
+		t.Errorf("music is %d samples long, want %d", got, want)
+	}
+}
+
+func TestMix(t *testing.T) {
+	mixed := Mix([]int16{1, 2, 30000}, []int16{10, 20, 30000, 4})
+	want := []int16{11, 22, 32767, 4}
+
+	if len(mixed) != len(want) {
+		t.Fatalf("mixed %v, want %v", mixed, want)
+	}
+	for n := range want {
+		if mixed[n] != want[n] {
+			t.Fatalf("mixed %v, want %v", mixed, want)
+		}
+	}
+}
+
+func TestWAV(t *testing.T) {
+	data := WAV([]int16{0, 1, -1, 32767})
+
+	if len(data) != 44+8 {
+		t.Fatalf("WAV is %d bytes, want %d", len(data), 44+8)
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[36:40]) != "data" {
+		t.Errorf("bad WAV header %q", data[:44])
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != SampleRate {
+		t.Errorf("sample rate is %d, want %d", rate, SampleRate)
+	}
+	if size := binary.LittleEndian.Uint32(data[40:]); size != 8 {
+		t.Errorf("data is %d bytes, want 8", size)
+	}
+	if sample := int16(binary.LittleEndian.Uint16(data[48:])); sample != -1 {
+		t.Errorf("third sample is %d, want -1", sample)
+	}
+}
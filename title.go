@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+)
+
+// titleIdleSince is when the title screen last saw a key, in seconds
+var titleIdleSince float64
+
+// MaxStartLevel is the highest level a marathon can start at
+const MaxStartLevel = 15
+
+// TitleEntry is a choice of the title screen menu
+type TitleEntry struct {
+	Name   string
+	Start  func()
+	Levels bool // Left and right pick the start level
+	Timing bool // T picks the timing profile
+}
+
+// titleEntries lists the choices of the title screen menu
+var titleEntries = []TitleEntry{
+	{"MARATHON", func() { StartMode(engine.Marathon{StartLevel: startLevel, Lines: marathonLines}) }, true, true},
+	{"ENDLESS", func() { StartMode(engine.Marathon{StartLevel: startLevel}) }, true, true},
+	{"SPRINT", func() { StartMode(engine.Sprint{Lines: sprintLines}) }, false, true},
+	{"ULTRA", func() { StartMode(engine.Ultra{Frames: int(ultraTime.Seconds() * engine.FrameRate)}) }, false, true},
+	{"CHEESE RACE", func() { StartMode(engine.CheeseRace{Lines: cheeseLines, Visible: 10}) }, false, true},
+	{"PUZZLES", InitPuzzles, false, true},
+	{"BOARD EDITOR", InitEditor, false, false},
+	{"VERSUS", InitVersus, false, true},
+}
+
+// titleTimings is the timing profile picked for each entry of the menu, the default one when missing
+var titleTimings = map[string]engine.Timing{}
+
+// EntryTiming returns the timing profile picked for an entry of the menu
+func EntryTiming(entry TitleEntry) engine.Timing {
+	if t, ok := titleTimings[entry.Name]; ok {
+		return t
+	}
+	return defaultTiming
+}
+
+// NextTiming returns the preset after a timing profile, going round
+func NextTiming(t engine.Timing) engine.Timing {
+	for n, preset := range engine.Timings {
+		if preset == t {
+			return engine.Timings[(n+1)%len(engine.Timings)]
+		}
+	}
+	return engine.Timings[0]
+}
+
+// titleSelection is the index of the selected menu entry
+var titleSelection int
+
+// InitTitle shows the title screen and restarts the idle timer
+func InitTitle() {
+	screen = TitleScreen
+	titleIdleSince = rl.GetTime()
+}
+
+// UpdateTitle moves through the menu, starting the demo after a while without input
+func UpdateTitle() {
+	if rl.IsKeyPressed(rl.KeyEnter) {
+		timing = EntryTiming(titleEntries[titleSelection])
+		titleEntries[titleSelection].Start()
+		return
+	}
+
+	if rl.IsKeyPressed(rl.KeyV) {
+		timing = EntryTiming(TitleEntry{Name: "VERSUS"})
+		InitVersus()
+		return
+	}
+
+	if rl.IsKeyPressed(rl.KeyDown) {
+		titleSelection = (titleSelection + 1) % len(titleEntries)
+	}
+	if rl.IsKeyPressed(rl.KeyUp) {
+		titleSelection = (titleSelection + len(titleEntries) - 1) % len(titleEntries)
+	}
+
+	if titleEntries[titleSelection].Levels {
+		if rl.IsKeyPressed(rl.KeyRight) {
+			startLevel = min(startLevel+1, MaxStartLevel)
+		}
+		if rl.IsKeyPressed(rl.KeyLeft) {
+			startLevel = max(startLevel-1, 1)
+		}
+	}
+
+	if entry := titleEntries[titleSelection]; entry.Timing && rl.IsKeyPressed(rl.KeyT) {
+		titleTimings[entry.Name] = NextTiming(EntryTiming(entry))
+	}
+
+	if rl.GetKeyPressed() != 0 {
+		titleIdleSince = rl.GetTime()
+	}
+
+	if attractDelay > 0 && rl.GetTime()-titleIdleSince >= attractDelay {
+		StartDemo()
+	}
+}
+
+// DrawTitle draws the title screen
+func DrawTitle() {
+	rl.BeginDrawing()
+
+	rl.ClearBackground(rl.RayWhite)
+
+	DrawCentered("TETRIS", layout.Center.Y-layout.Size(120), 60, rl.Black)
+
+	for n, entry := range titleEntries {
+		color := rl.Gray
+		text := entry.Name
+		if n == titleSelection {
+			color = rl.Maroon
+			if entry.Levels {
+				text = fmt.Sprintf("%s - LEVEL %d", text, startLevel)
+			}
+			if entry.Timing {
+				text = fmt.Sprintf("%s - %s", text, EntryTiming(entry).Name)
+			}
+			text = "> " + text + " <"
+		}
+		DrawCentered(text, layout.Center.Y+layout.Size(30*float32(n)-50), 20, color)
+	}
+
+	help := "[UP]/[DOWN] TO CHOOSE, [LEFT]/[RIGHT] FOR THE LEVEL, [T] FOR THE TIMING, [ENTER] TO PLAY"
+	DrawCentered(help, layout.Center.Y+layout.Size(195), 10, rl.Gray)
+
+	rl.EndDrawing()
+}
+
+// StartMode starts a single player game following the rules of a mode
+func StartMode(m engine.Mode) {
+	screen = GameplayScreen
+	mode = m
+	InitGame()
+}
+
+// StartDemo starts an endless game played by the AI, shown until a key is pressed
+func StartDemo() {
+	screen = DemoScreen
+	mode = nil
+	timing = defaultTiming
+	InitGame()
+}
+
+// UpdateDemo plays the demo game and goes back to the title on any key or when the demo is over
+func UpdateDemo() {
+	if rl.GetKeyPressed() != 0 || game.GameOver {
+		InitTitle()
+		return
+	}
+
+	for i := 0; i < steps && !game.GameOver; i++ {
+		game.Step(bot.Next(game))
+		gameEffects.Observe(game)
+		gameSounds.Observe(game)
+		PublishSpectators()
+	}
+	gameEffects.Update(rl.GetFrameTime())
+}
+
+// DrawDemoOverlay marks the game on screen as a demo
+func DrawDemoOverlay() {
+	// Centred over the grid
+	x := layout.Grid.X + engine.GridHorizontalSize*layout.Square/2
+	rl.DrawText("DEMO", int32(x)-rl.MeasureText("DEMO", layout.Text(40))/2, int32(layout.Grid.Y-layout.Size(5)), layout.Text(40), rl.Maroon)
+	rl.DrawText("PRESS ANY KEY", int32(x)-rl.MeasureText("PRESS ANY KEY", layout.Text(10))/2, int32(layout.Grid.Y+layout.Size(35)), layout.Text(10), rl.Maroon)
+}
@@ -0,0 +1,190 @@
+// Package tune evolves the weights of the AI heuristic with the cross-entropy
+// method: every generation samples weight vectors around a mean, plays seeded
+// games with each of them on the engine and moves the mean towards the best.
+package tune
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+// Config of a tuning run
+type Config struct {
+	Set         *engine.PieceSet
+	Generations int
+	Population  int     // Weight vectors tried every generation
+	Elite       int     // Best vectors the next generation is drawn around
+	Games       int     // Games played by every vector, the same seeds for all
+	MaxPieces   int     // Pieces after which a game stops
+	Workers     int     // Games played at the same time
+	Lookahead   bool    // Let the players look at the incoming piece, slower
+	Noise       float64 // Extra spread added every generation, halved each time
+	Seed        int64
+	Start       ai.Weights
+}
+
+// Validate reports the settings a run cannot work with
+func (c Config) Validate() error {
+	if c.Population < 1 {
+		return fmt.Errorf("population of %d, at least one vector is needed", c.Population)
+	}
+	if c.Elite < 1 || c.Elite > c.Population {
+		return fmt.Errorf("elite of %d out of a population of %d, it goes from 1 to the population", c.Elite, c.Population)
+	}
+	if c.Games < 1 {
+		return fmt.Errorf("%d games, every vector has to play at least one", c.Games)
+	}
+	return nil
+}
+
+// Candidate is a weight vector and the average lines it cleared
+type Candidate struct {
+	Weights ai.Weights
+	Fitness float64
+}
+
+// Generation sums up one generation of a run
+type Generation struct {
+	Number int
+	Best   Candidate
+	Mean   float64 // Average fitness of the population
+	Worst  float64
+	Center ai.Weights // Mean of the distribution the population was drawn from
+	Spread ai.Weights // Its standard deviation
+}
+
+// Run tunes the weights, calling report after every generation, and returns
+// the best candidate found. The config must pass Validate.
+func Run(config Config, report func(Generation)) Candidate {
+	random := rand.New(rand.NewSource(config.Seed))
+
+	mean := config.Start.Vector()
+	deviation := make([]float64, len(mean))
+	for i := range deviation {
+		deviation[i] = 0.5
+	}
+	noise := config.Noise
+
+	best := Candidate{Fitness: math.Inf(-1)}
+
+	for n := 1; n <= config.Generations; n++ {
+		population := make([]Candidate, config.Population)
+		for i := range population {
+			v := make([]float64, len(mean))
+			for k := range v {
+				v[k] = mean[k] + random.NormFloat64()*deviation[k]
+			}
+			population[i].Weights = ai.WeightsFromVector(v)
+		}
+
+		// Every candidate plays the same games this generation
+		seeds := make([]int64, config.Games)
+		for i := range seeds {
+			seeds[i] = random.Int63()
+		}
+
+		Evaluate(config, population, seeds)
+
+		sort.Slice(population, func(i, j int) bool {
+			return population[i].Fitness > population[j].Fitness
+		})
+
+		generation := Generation{
+			Number: n,
+			Best:   population[0],
+			Worst:  population[len(population)-1].Fitness,
+			Center: ai.WeightsFromVector(mean),
+			Spread: ai.WeightsFromVector(deviation),
+		}
+		for _, c := range population {
+			generation.Mean += c.Fitness / float64(len(population))
+		}
+		if report != nil {
+			report(generation)
+		}
+
+		if population[0].Fitness > best.Fitness {
+			best = population[0]
+		}
+
+		// Fit the distribution to the elite
+		elite := population[:min(config.Elite, len(population))]
+		for k := range mean {
+			sum := 0.0
+			for _, c := range elite {
+				sum += c.Weights.Vector()[k]
+			}
+			mean[k] = sum / float64(len(elite))
+
+			variance := 0.0
+			for _, c := range elite {
+				d := c.Weights.Vector()[k] - mean[k]
+				variance += d * d
+			}
+			deviation[k] = math.Sqrt(variance/float64(len(elite))) + noise
+		}
+		noise /= 2
+	}
+
+	return best
+}
+
+// Evaluate plays every seed with every candidate over parallel workers and
+// sets each fitness to the average lines cleared
+func Evaluate(config Config, population []Candidate, seeds []int64) {
+	type job struct {
+		candidate int
+		seed      int64
+	}
+
+	jobs := make(chan job)
+	lines := make([]int, len(population))
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+
+	for w := 0; w < max(config.Workers, 1); w++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := range jobs {
+				result := PlayGame(config, population[j.candidate].Weights, j.seed)
+
+				mutex.Lock()
+				lines[j.candidate] += result
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := range population {
+		for _, seed := range seeds {
+			jobs <- job{i, seed}
+		}
+	}
+	close(jobs)
+	wait.Wait()
+
+	for i := range population {
+		population[i].Fitness = float64(lines[i]) / float64(len(seeds))
+	}
+}
+
+// PlayGame plays one seeded game with the weights and returns the lines cleared
+func PlayGame(config Config, weights ai.Weights, seed int64) int {
+	g := engine.NewGame(config.Set, seed)
+	player := ai.NewPlayer(weights)
+	player.Lookahead = config.Lookahead
+
+	for !g.GameOver && (config.MaxPieces == 0 || g.Pieces < config.MaxPieces) {
+		g.Step(player.Next(g))
+	}
+
+	return g.Lines
+}
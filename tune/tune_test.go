@@ -0,0 +1,95 @@
+package tune
+
+import (
+	"testing"
+
+	"tetris/main/ai"
+	"tetris/main/engine"
+)
+
+// testConfig is a run small enough for the tests
+func testConfig() Config {
+	return Config{
+		Set:         engine.DefaultPieceSet(),
+		Generations: 3,
+		Population:  8,
+		Elite:       3,
+		Games:       2,
+		MaxPieces:   60,
+		Workers:     4,
+		Noise:       0.2,
+		Seed:        1,
+	}
+}
+
+func TestEvaluateIsDeterministic(t *testing.T) {
+	config := testConfig()
+	seeds := []int64{1, 2, 3}
+
+	weights := ai.DefaultWeights()
+	population := []Candidate{{Weights: weights}, {Weights: ai.Weights{}}, {Weights: weights}}
+	Evaluate(config, population, seeds)
+
+	again := []Candidate{{Weights: weights}, {Weights: ai.Weights{}}, {Weights: weights}}
+	Evaluate(config, again, seeds)
+
+	for i := range population {
+		if population[i].Fitness != again[i].Fitness {
+			t.Errorf("candidate %d scored %v then %v on the same seeds", i, population[i].Fitness, again[i].Fitness)
+		}
+	}
+	if population[0].Fitness != population[2].Fitness {
+		t.Errorf("the same weights scored %v and %v", population[0].Fitness, population[2].Fitness)
+	}
+	if population[0].Fitness == 0 {
+		t.Error("the default weights cleared no lines")
+	}
+}
+
+func TestRunImproves(t *testing.T) {
+	config := testConfig()
+
+	generations := 0
+	best := Run(config, func(g Generation) {
+		generations++
+		if g.Best.Fitness < g.Mean || g.Mean < g.Worst {
+			t.Errorf("generation %d: best %v, mean %v, worst %v out of order", g.Number, g.Best.Fitness, g.Mean, g.Worst)
+		}
+	})
+	if generations != config.Generations {
+		t.Errorf("reported %d generations, want %d", generations, config.Generations)
+	}
+
+	// The run starts from all zero weights; both play the same fresh games
+	population := []Candidate{{Weights: config.Start}, {Weights: best.Weights}}
+	Evaluate(config, population, []int64{11, 12, 13})
+
+	if population[1].Fitness < population[0].Fitness {
+		t.Errorf("tuned weights clear %v lines, fewer than the %v of the starting mean", population[1].Fitness, population[0].Fitness)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+	}{
+		{"no population", func(c *Config) { c.Population = 0 }},
+		{"no elite", func(c *Config) { c.Elite = 0 }},
+		{"negative elite", func(c *Config) { c.Elite = -2 }},
+		{"elite past the population", func(c *Config) { c.Elite = c.Population + 1 }},
+		{"no games", func(c *Config) { c.Games = 0 }},
+	}
+
+	for _, test := range tests {
+		config := testConfig()
+		test.change(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: config accepted", test.name)
+		}
+	}
+}
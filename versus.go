@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"tetris/main/engine"
+	"tetris/main/versus"
+)
+
+// match is the versus match being played
+var match *versus.Match
+
+// versusEffects are the effects of both games of the match
+var versusEffects [2]Effects
+
+// InitVersus starts a split-screen match between two players on one keyboard
+func InitVersus() {
+	screen = VersusScreen
+	pause = false
+	versusEffects = [2]Effects{}
+	versusSounds = [2]SoundWatcher{}
+
+	match = versus.NewMatch(versus.Config{
+		Set:          pieceSet,
+		Seed:         time.Now().UnixNano(),
+		GarbageDelay: garbageDelay,
+		Timing:       timing,
+	})
+}
+
+// UpdateVersus updates both games for one frame
+func UpdateVersus() {
+	if match.Over() {
+		if rl.IsKeyPressed(rl.KeyEnter) {
+			InitVersus()
+		}
+		return
+	}
+
+	if rl.IsKeyPressed(rl.KeyP) {
+		pause = !pause
+	}
+
+	if !pause {
+		playerInputs[0].Hold(ReadInput(PlayerOneKeys))
+		playerInputs[1].Hold(ReadInput(PlayerTwoKeys))
+
+		for i := 0; i < steps && !match.Over(); i++ {
+			match.Step([2]engine.Input{playerInputs[0].Take(), playerInputs[1].Take()})
+			ObserveVersus()
+		}
+		UpdateVersusEffects()
+	}
+}
+
+// ObserveVersus starts the effects and sounds of the last step of both games and shows it to the spectators
+func ObserveVersus() {
+	for n, g := range match.Players {
+		versusEffects[n].Observe(g)
+		versusSounds[n].Observe(g)
+	}
+	PublishSpectators()
+}
+
+// UpdateVersusEffects moves the effects of both games on by the time of the last frame
+func UpdateVersusEffects() {
+	for n := range versusEffects {
+		versusEffects[n].Update(rl.GetFrameTime())
+	}
+}
+
+// DrawVersus draws both games, each with its incoming piece on the outer side
+func DrawVersus() {
+	rl.BeginDrawing()
+
+	rl.ClearBackground(rl.RayWhite)
+
+	top := layout.Center.Y - (engine.GridVerticalSize*layout.Square)/2
+	width := engine.GridHorizontalSize * layout.Square
+	preview := engine.MaxPieceSize * layout.Square
+	gap := layout.Size(10)
+	text := layout.Text(10)
+
+	for n, g := range match.Players {
+		offset := rl.Vector2{X: layout.Center.X - width - gap, Y: top}
+		previewX := offset.X - preview - 2*gap
+		if n == 1 {
+			offset.X = layout.Center.X + gap
+			previewX = offset.X + width + 2*gap
+		}
+
+		shaken := rl.Vector2Add(offset, versusEffects[n].Shake())
+		DrawGrid(g, shaken)
+		versusEffects[n].Draw(shaken)
+
+		name := fmt.Sprintf("PLAYER %d", n+1)
+		if screen == NetplayScreen && n == session.Local {
+			name += " (YOU)"
+		}
+		rl.DrawText(name, int32(previewX), int32(top), text, rl.Gray)
+		DrawPiecePreview(g.Set.Pieces[g.IncomingType].Rotations[0], rl.Vector2{X: previewX, Y: top + 2*gap})
+		rl.DrawText(fmt.Sprintf("LINES:  %04d", g.Lines), int32(previewX), int32(top+preview+3*gap), text, rl.Gray)
+		rl.DrawText(fmt.Sprintf("SENT:   %04d", g.LinesSent), int32(previewX), int32(top+preview+4.5*gap), text, rl.Gray)
+
+		rl.DrawText("HOLD:", int32(previewX), int32(top+preview+7*gap), text, rl.Gray)
+		if g.HoldType >= 0 {
+			DrawPiecePreview(g.Set.Pieces[g.HoldType].Rotations[0], rl.Vector2{X: previewX, Y: top + preview + 9*gap})
+		}
+	}
+
+	if match.Over() || networkError != nil {
+		result := "DRAW"
+		if networkError != nil {
+			result = "CONNECTION LOST"
+		} else if match.Winner >= 0 {
+			result = fmt.Sprintf("PLAYER %d WINS", match.Winner+1)
+		}
+
+		prompt := "PRESS [ENTER] TO PLAY AGAIN"
+		if screen == NetplayScreen {
+			prompt = "PRESS [ENTER] TO RETURN TO THE TITLE"
+		}
+
+		rl.DrawText(result, int32(layout.Center.X)-rl.MeasureText(result, layout.Text(40))/2, int32(layout.Center.Y-layout.Size(60)), layout.Text(40), rl.Maroon)
+		rl.DrawText(prompt, int32(layout.Center.X)-rl.MeasureText(prompt, layout.Text(20))/2, int32(layout.Center.Y), layout.Text(20), rl.Gray)
+	} else if pause {
+		rl.DrawText("GAME PAUSED", int32(layout.Center.X)-rl.MeasureText("GAME PAUSED", layout.Text(40))/2, int32(layout.Center.Y-layout.Size(40)), layout.Text(40), rl.Gray)
+	}
+
+	rl.EndDrawing()
+}
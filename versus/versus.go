@@ -0,0 +1,74 @@
+// Package versus runs two games side by side, sending the garbage each
+// player's line clears produce to the other one.
+package versus
+
+import "tetris/main/engine"
+
+// Config of a versus match
+type Config struct {
+	Set          *engine.PieceSet
+	Seed         int64
+	GarbageDelay int           // Frames garbage waits before it can enter the grid
+	Timing       engine.Timing // Delays of both games, ClassicTiming when left out
+}
+
+// Match is a game between two players
+type Match struct {
+	Config
+	Players [2]*engine.Game
+	Winner  int // -1 while playing or on a draw
+
+	sent   [2]int
+	random engine.Random
+}
+
+// NewMatch starts a match where both players get the same pieces
+func NewMatch(config Config) *Match {
+	if config.Timing == (engine.Timing{}) {
+		config.Timing = engine.ClassicTiming
+	}
+
+	m := &Match{
+		Config:  config,
+		Players: [2]*engine.Game{engine.NewGame(config.Set, config.Seed), engine.NewGame(config.Set, config.Seed)},
+		Winner:  -1,
+		random:  engine.NewRandom(config.Seed + 1),
+	}
+	for _, g := range m.Players {
+		g.Timing = config.Timing
+	}
+
+	return m
+}
+
+// Step updates both games for one frame and trades the garbage they sent
+func (m *Match) Step(inputs [2]engine.Input) {
+	if m.Over() {
+		return
+	}
+
+	for n, g := range m.Players {
+		g.Step(inputs[n])
+	}
+
+	for n, g := range m.Players {
+		if rows := g.LinesSent - m.sent[n]; rows > 0 {
+			// Every attack gets its own hole
+			hole := 1 + m.random.Intn(engine.GridHorizontalSize-2)
+			m.Players[1-n].ReceiveGarbage(rows, hole, m.GarbageDelay)
+		}
+		m.sent[n] = g.LinesSent
+	}
+
+	switch {
+	case m.Players[0].GameOver && !m.Players[1].GameOver:
+		m.Winner = 1
+	case m.Players[1].GameOver && !m.Players[0].GameOver:
+		m.Winner = 0
+	}
+}
+
+// Over reports whether a player has topped out
+func (m *Match) Over() bool {
+	return m.Players[0].GameOver || m.Players[1].GameOver
+}
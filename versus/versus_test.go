@@ -0,0 +1,73 @@
+package versus
+
+import (
+	"strings"
+	"testing"
+
+	"tetris/main/engine"
+)
+
+// setBoard puts a board written in board notation in a player's grid
+func setBoard(t *testing.T, g *engine.Game, board string) {
+	t.Helper()
+
+	grid, err := engine.ParseBoard(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetGrid(grid)
+}
+
+func TestGarbageGoesToTheOpponent(t *testing.T) {
+	m := NewMatch(Config{Set: engine.DefaultPieceSet(), Seed: 1, GarbageDelay: 30})
+
+	// Player 1 clears two lines with the O piece, player 2 does nothing
+	setBoard(t, m.Players[0], "####..####\n####..####")
+	m.Players[0].IncomingType = m.Players[0].Set.Index("O")
+
+	for i := 0; i < 1000 && m.Players[0].Lines == 0; i++ {
+		m.Step([2]engine.Input{engine.InputDown, 0})
+	}
+
+	sent := engine.GarbageAttack[2]
+	if m.Players[0].LinesSent != sent {
+		t.Fatalf("player 1 sent %d rows, want %d", m.Players[0].LinesSent, sent)
+	}
+
+	opponent := m.Players[1]
+	if opponent.PendingGarbage != 1 || opponent.Garbage[0].Rows != sent {
+		t.Fatalf("player 2 has %d rows pending in %d attacks, want %d in 1", opponent.PendingGarbageRows(), opponent.PendingGarbage, sent)
+	}
+	if hole := opponent.Garbage[0].Hole; hole < 1 || hole > engine.GridHorizontalSize-2 {
+		t.Errorf("hole in column %d, outside the grid", hole)
+	}
+	if opponent.Garbage[0].Delay != m.GarbageDelay {
+		t.Errorf("garbage waits %d frames, want %d", opponent.Garbage[0].Delay, m.GarbageDelay)
+	}
+	if m.Players[0].PendingGarbage != 0 {
+		t.Error("the sender received its own garbage")
+	}
+
+	// The rows are only handed over once
+	for i := 0; i < 5; i++ {
+		m.Step([2]engine.Input{})
+	}
+	if rows := opponent.PendingGarbageRows() + opponent.GarbageAdded; rows != sent {
+		t.Errorf("player 2 got %d rows in all, want %d", rows, sent)
+	}
+}
+
+func TestWinner(t *testing.T) {
+	m := NewMatch(Config{Set: engine.DefaultPieceSet(), Seed: 1})
+	if m.Timing != engine.ClassicTiming || m.Players[1].Timing != engine.ClassicTiming {
+		t.Error("a match without timing does not play the classic one")
+	}
+
+	// Player 2's stack reaches the top
+	setBoard(t, m.Players[1], strings.Repeat("#.........\n", engine.GridVerticalSize-2))
+	m.Step([2]engine.Input{})
+
+	if !m.Over() || m.Winner != 0 {
+		t.Errorf("over %v with winner %d, want player 1 to win", m.Over(), m.Winner)
+	}
+}